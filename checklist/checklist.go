@@ -0,0 +1,352 @@
+// Package checklist verifies scientific names against a local reference
+// checklist, for example a dump of the Catalogue of Life or of the GBIF
+// Backbone Taxonomy. Verification does not need network access.
+//
+// A checklist is either a tab-separated file with a header line, or a
+// Darwin Core Archive (a zip file with meta.xml). Columns are recognized by
+// Darwin Core terms: scientificName is required, taxonID,
+// acceptedNameUsageID and canonicalName are optional. The whole checklist
+// is loaded into memory.
+package checklist
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gnames/htindex/parser"
+	"github.com/gnames/htindex/verifier"
+)
+
+// Checklist keeps names of a reference checklist in memory. It implements
+// verifier.Verifier and is safe for concurrent use after it is loaded.
+type Checklist struct {
+	// Source is the title of the checklist. It is taken from the metadata
+	// of a Darwin Core Archive, or from the name of the file.
+	Source  string
+	records []record
+	// byName, byCanonical and byID map keys to indices of records.
+	byName      map[string]int
+	byCanonical map[string]int
+	byID        map[string]int
+}
+
+// record is a name of a checklist.
+type record struct {
+	id         string
+	name       string
+	acceptedID string
+}
+
+// accepted is true if the record is not a synonym.
+func (r record) accepted() bool {
+	return r.acceptedID == "" || r.acceptedID == r.id
+}
+
+// Load reads a checklist from a Darwin Core Archive (a file with '.zip'
+// extension) or from a tab-separated file.
+func Load(path string) (*Checklist, error) {
+	c := &Checklist{
+		Source:      strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		byName:      make(map[string]int),
+		byCanonical: make(map[string]int),
+		byID:        make(map[string]int),
+	}
+	var err error
+	if strings.ToLower(filepath.Ext(path)) == ".zip" {
+		err = c.loadDwCA(path)
+	} else {
+		err = c.loadTSV(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot load checklist %s: %s", path, err)
+	}
+	return c, nil
+}
+
+// Len returns the number of names in the checklist.
+func (c *Checklist) Len() int {
+	return len(c.records)
+}
+
+// Verify finds names in the checklist. It never returns an error.
+func (c *Checklist) Verify(names []verifier.Name) ([]verifier.Match, error) {
+	res := make([]verifier.Match, len(names))
+	for i, n := range names {
+		res[i] = c.Match(n.Name, n.Canonical)
+	}
+	return res, nil
+}
+
+// Match finds a name in the checklist. It tries the name-string first,
+// and then its canonical form. Accepted names are preferred for canonical
+// matches.
+func (c *Checklist) Match(name, canonical string) verifier.Match {
+	i, ok := c.byName[name]
+	matchType := verifier.Exact
+	if !ok && canonical != "" {
+		i, ok = c.byCanonical[canonical]
+		matchType = verifier.Canonical
+	}
+	if !ok {
+		return verifier.Match{Type: verifier.NoMatch, DataSource: c.Source}
+	}
+	r := c.records[i]
+	acc := r
+	if !r.accepted() {
+		if j, ok := c.byID[r.acceptedID]; ok {
+			acc = c.records[j]
+		}
+	}
+	return verifier.Match{
+		ID:           r.id,
+		MatchedName:  r.name,
+		AcceptedID:   acc.id,
+		AcceptedName: acc.name,
+		Type:         matchType,
+		DataSource:   c.Source,
+	}
+}
+
+// add saves a record and indexes it.
+func (c *Checklist) add(r record, canonical string) {
+	i := len(c.records)
+	c.records = append(c.records, r)
+	if _, ok := c.byName[r.name]; !ok {
+		c.byName[r.name] = i
+	}
+	if r.id != "" {
+		c.byID[r.id] = i
+	}
+	if canonical == "" {
+		return
+	}
+	if j, ok := c.byCanonical[canonical]; !ok ||
+		(!c.records[j].accepted() && r.accepted()) {
+		c.byCanonical[canonical] = i
+	}
+}
+
+// columns keeps indices of recognized fields, -1 means that a field is
+// absent.
+type columns struct {
+	id, name, acceptedID, canonical int
+}
+
+// newColumns finds recognized Darwin Core terms in field names. Terms can
+// be given as URIs, with a namespace prefix, or as simple names.
+func newColumns(fields []string) (columns, error) {
+	cols := columns{id: -1, name: -1, acceptedID: -1, canonical: -1}
+	for i, f := range fields {
+		term := f
+		if j := strings.LastIndexAny(term, "/:"); j > -1 {
+			term = term[j+1:]
+		}
+		switch strings.ToLower(strings.TrimSpace(term)) {
+		case "taxonid":
+			cols.id = i
+		case "scientificname":
+			cols.name = i
+		case "acceptednameusageid":
+			cols.acceptedID = i
+		case "canonicalname":
+			cols.canonical = i
+		}
+	}
+	if cols.name == -1 {
+		return cols, fmt.Errorf("scientificName field is missing")
+	}
+	return cols, nil
+}
+
+// addRows reads rows with fields in the order of cols and adds them to the
+// checklist. If canonical forms are not given, names are parsed.
+func (c *Checklist) addRows(rows *rowReader, cols columns) error {
+	gnp := parser.New()
+	for {
+		row, err := rows.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		r := record{
+			id:         field(row, cols.id),
+			name:       field(row, cols.name),
+			acceptedID: field(row, cols.acceptedID),
+		}
+		if r.name == "" {
+			continue
+		}
+		canonical := field(row, cols.canonical)
+		if canonical == "" {
+			canonical = gnp.Parse(r.name).Canonical
+		}
+		c.add(r, canonical)
+	}
+}
+
+func field(row []string, i int) string {
+	if i < 0 || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
+
+func (c *Checklist) loadTSV(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	rows := newRowReader(f, "\t", false)
+	header, err := rows.read()
+	if err != nil {
+		return err
+	}
+	cols, err := newColumns(header)
+	if err != nil {
+		return err
+	}
+	return c.addRows(rows, cols)
+}
+
+// meta is the part of meta.xml of a Darwin Core Archive that describes
+// its core file.
+type meta struct {
+	Core struct {
+		FieldsTerminatedBy string `xml:"fieldsTerminatedBy,attr"`
+		FieldsEnclosedBy   string `xml:"fieldsEnclosedBy,attr"`
+		IgnoreHeaderLines  int    `xml:"ignoreHeaderLines,attr"`
+		Location           string `xml:"files>location"`
+		ID                 struct {
+			Index int `xml:"index,attr"`
+		} `xml:"id"`
+		Fields []struct {
+			Index int    `xml:"index,attr"`
+			Term  string `xml:"term,attr"`
+		} `xml:"field"`
+	} `xml:"core"`
+}
+
+// eml is the part of eml.xml of a Darwin Core Archive with the title of
+// the dataset.
+type eml struct {
+	Title string `xml:"dataset>title"`
+}
+
+func (c *Checklist) loadDwCA(path string) error {
+	z, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer z.Close()
+	files := make(map[string]*zip.File)
+	for _, f := range z.File {
+		files[filepath.Base(f.Name)] = f
+	}
+
+	var m meta
+	if err = readXML(files["meta.xml"], &m); err != nil {
+		return fmt.Errorf("bad meta.xml: %s", err)
+	}
+	var e eml
+	if readXML(files["eml.xml"], &e) == nil && e.Title != "" {
+		c.Source = strings.Join(strings.Fields(e.Title), " ")
+	}
+
+	fields := make([]string, len(m.Core.Fields))
+	for _, v := range m.Core.Fields {
+		if v.Index >= len(fields) {
+			fields = append(fields, make([]string, v.Index-len(fields)+1)...)
+		}
+		fields[v.Index] = v.Term
+	}
+	cols, err := newColumns(fields)
+	if err != nil {
+		return err
+	}
+	if cols.id == -1 {
+		cols.id = m.Core.ID.Index
+	}
+
+	core, ok := files[filepath.Base(m.Core.Location)]
+	if !ok {
+		return fmt.Errorf("core file %s is missing", m.Core.Location)
+	}
+	r, err := core.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	sep := unescape(m.Core.FieldsTerminatedBy)
+	if sep == "" {
+		sep = "\t"
+	}
+	rows := newRowReader(r, sep, m.Core.FieldsEnclosedBy != "")
+	for i := 0; i < m.Core.IgnoreHeaderLines; i++ {
+		if _, err = rows.read(); err != nil {
+			return err
+		}
+	}
+	return c.addRows(rows, cols)
+}
+
+func readXML(f *zip.File, obj interface{}) error {
+	if f == nil {
+		return fmt.Errorf("file is missing")
+	}
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return xml.NewDecoder(r).Decode(obj)
+}
+
+// unescape converts escaped separators of meta.xml ('\t') to characters.
+func unescape(s string) string {
+	return strings.NewReplacer(`\t`, "\t", `\n`, "\n").Replace(s)
+}
+
+// rowReader reads rows of delimited text. Fields that are not enclosed
+// in quotes can contain quotes, which is common for scientific names.
+type rowReader struct {
+	sep     string
+	scanner *bufio.Scanner
+	csv     *csv.Reader
+}
+
+func newRowReader(r io.Reader, sep string, enclosed bool) *rowReader {
+	if enclosed {
+		cr := csv.NewReader(r)
+		cr.Comma = []rune(sep)[0]
+		cr.FieldsPerRecord = -1
+		cr.LazyQuotes = true
+		return &rowReader{sep: sep, csv: cr}
+	}
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	return &rowReader{sep: sep, scanner: s}
+}
+
+func (rr *rowReader) read() ([]string, error) {
+	if rr.csv != nil {
+		return rr.csv.Read()
+	}
+	if !rr.scanner.Scan() {
+		if err := rr.scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	line := strings.TrimRight(rr.scanner.Text(), "\r")
+	return strings.Split(line, rr.sep), nil
+}
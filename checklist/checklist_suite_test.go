@@ -0,0 +1,13 @@
+package checklist_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestChecklist(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Checklist Suite")
+}
@@ -0,0 +1,75 @@
+package checklist_test
+
+import (
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	. "github.com/gnames/htindex/checklist"
+	"github.com/gnames/htindex/verifier"
+)
+
+var _ = Describe("Checklist", func() {
+	Describe("Load", func() {
+		It("loads a tab-separated file", func() {
+			c, err := Load(filepath.Join("testdata", "checklist.tsv"))
+			Expect(err).To(BeNil())
+			Expect(c.Len()).To(Equal(6))
+			Expect(c.Source).To(Equal("checklist"))
+		})
+
+		It("loads a Darwin Core Archive", func() {
+			c, err := Load(filepath.Join("testdata", "checklist.zip"))
+			Expect(err).To(BeNil())
+			Expect(c.Len()).To(Equal(3))
+			Expect(c.Source).To(Equal("Test Backbone"))
+		})
+
+		It("verifies a batch of names", func() {
+			c, err := Load(filepath.Join("testdata", "checklist.tsv"))
+			Expect(err).To(BeNil())
+			var v verifier.Verifier = c
+			res, err := v.Verify([]verifier.Name{
+				{Name: "Crania", Canonical: "Crania"},
+				{Name: "Leonora", Canonical: "Leonora"},
+			})
+			Expect(err).To(BeNil())
+			Expect(len(res)).To(Equal(2))
+			Expect(res[0].Type).To(Equal(verifier.Canonical))
+			Expect(res[1].Type).To(Equal(verifier.NoMatch))
+		})
+
+		It("fails on a missing file", func() {
+			_, err := Load(filepath.Join("testdata", "nofile.tsv"))
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	DescribeTable("Verify",
+		func(file, name, canonical, matchType, id, accepted string) {
+			c, err := Load(filepath.Join("testdata", file))
+			Expect(err).To(BeNil())
+			m := c.Match(name, canonical)
+			Expect(m.Type).To(Equal(matchType))
+			Expect(m.ID).To(Equal(id))
+			Expect(m.AcceptedName).To(Equal(accepted))
+			Expect(m.DataSource).To(Equal(c.Source))
+		},
+		Entry("exact", "checklist.tsv", "Crania Retzius, 1781", "Crania",
+			verifier.Exact, "3", "Crania Retzius, 1781"),
+		Entry("canonical", "checklist.tsv", "Crania", "Crania",
+			verifier.Canonical, "3", "Crania Retzius, 1781"),
+		Entry("synonym", "checklist.tsv", "Gasterosteus saltatrix",
+			"Gasterosteus saltatrix", verifier.Canonical, "2",
+			"Pomatomus saltatrix (Linnaeus, 1766)"),
+		Entry("prefers accepted", "checklist.tsv", "Quercus alba",
+			"Quercus alba", verifier.Canonical, "6", "Quercus alba L."),
+		Entry("no match", "checklist.tsv", "Leonora", "Leonora",
+			verifier.NoMatch, "", ""),
+		Entry("archive", "checklist.zip", "Gasterosteus saltatrix",
+			"Gasterosteus saltatrix", verifier.Canonical, "b2",
+			"Pomatomus saltatrix (Linnaeus, 1766)"),
+	)
+})
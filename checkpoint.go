@@ -0,0 +1,158 @@
+package htindex
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// checkpointFile is the name of a journal that records titles that were
+// completely written to the output.
+const checkpointFile = "checkpoint.csv"
+
+// checkpointFiles are the output files whose sizes are recorded in the
+// checkpoint journal, in the order of the journal fields.
+var checkpointFiles = []string{
+	"results.csv", "titles.csv", "summary.csv", "pages.csv",
+}
+
+// checkpoint keeps the journal of completed titles. Every record contains
+// the ID of a title and the sizes of checkpointFiles right after the title
+// was written. These sizes allow to get rid of partially written data when
+// an interrupted run is resumed.
+type checkpoint struct {
+	f *os.File
+	w *csv.Writer
+}
+
+// newCheckpoint opens the checkpoint journal. During a resumed run new
+// records are appended to the journal, otherwise the journal starts anew.
+func (hti *HTindex) newCheckpoint() (*checkpoint, error) {
+	path := filepath.Join(hti.OutputPath, checkpointFile)
+	flag := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if hti.Resume {
+		flag = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	f, err := os.OpenFile(path, flag, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &checkpoint{f: f, w: csv.NewWriter(f)}, nil
+}
+
+// add records a title as completely written to the output. Sizes follow
+// the order of checkpointFiles.
+func (c *checkpoint) add(titleID string, sizes ...int64) error {
+	row := make([]string, len(sizes)+1)
+	row[0] = titleID
+	for i, v := range sizes {
+		row[i+1] = strconv.FormatInt(v, 10)
+	}
+	c.w.Write(row)
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *checkpoint) close() error {
+	c.w.Flush()
+	return c.f.Close()
+}
+
+// restoreCheckpoint reads the checkpoint journal of a previous run and
+// returns IDs of titles that do not need to be processed again. It also
+// truncates checkpointFiles to the state of the last completed title,
+// removing data that might be left by an interrupted run. If the run is not
+// resumed, it returns an empty set.
+func (hti *HTindex) restoreCheckpoint() (map[string]struct{}, error) {
+	done := make(map[string]struct{})
+	if !hti.Resume {
+		return done, nil
+	}
+	if hti.Sink != nil || hti.Format != CSV {
+		return nil, fmt.Errorf("resume works only with csv format")
+	}
+	sizes := make([]int64, len(checkpointFiles))
+	var rows [][]string
+	data, err := ioutil.ReadFile(filepath.Join(hti.OutputPath, checkpointFile))
+	if os.IsNotExist(err) {
+		return done, hti.truncateOutput(sizes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the last record might be broken if the run was interrupted while it
+	// was written. Only records that end with a new line are complete.
+	data = data[:bytes.LastIndexByte(data, '\n')+1]
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = len(checkpointFiles) + 1
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		rowSizes, ok := checkpointSizes(row, err)
+		if !ok {
+			break
+		}
+		done[row[0]] = struct{}{}
+		rows = append(rows, row)
+		sizes = rowSizes
+	}
+	if err = hti.rewriteCheckpoint(rows); err != nil {
+		return nil, err
+	}
+	return done, hti.truncateOutput(sizes)
+}
+
+// checkpointSizes returns sizes of checkpointFiles from a record of the
+// checkpoint journal. It returns false if the record is broken, so records
+// after it cannot be trusted.
+func checkpointSizes(row []string, err error) ([]int64, bool) {
+	if err != nil {
+		return nil, false
+	}
+	res := make([]int64, len(checkpointFiles))
+	for i := range res {
+		if res[i], err = strconv.ParseInt(row[i+1], 10, 64); err != nil {
+			return nil, false
+		}
+	}
+	return res, true
+}
+
+// truncateOutput removes data written after the last completed title.
+// Sizes follow the order of checkpointFiles.
+func (hti *HTindex) truncateOutput(sizes []int64) error {
+	for i, name := range checkpointFiles {
+		err := os.Truncate(filepath.Join(hti.OutputPath, name), sizes[i])
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewriteCheckpoint saves only valid records to the checkpoint journal, so
+// new records would not be appended to a broken line.
+func (hti *HTindex) rewriteCheckpoint(rows [][]string) error {
+	path := filepath.Join(hti.OutputPath, checkpointFile)
+	f, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	if err = w.WriteAll(rows); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
@@ -0,0 +1,37 @@
+package htindex
+
+import "fmt"
+
+// Format determines how titles, names and errors are saved.
+type Format int
+
+// Supported output formats.
+const (
+	// CSV saves data to results.csv, titles.csv and errors.csv files.
+	CSV Format = iota
+	// Parquet saves data to results.parquet, titles.parquet and
+	// errors.parquet files with typed columns.
+	Parquet
+	// JSONL saves data to titles.jsonl and errors.jsonl files in JSON Lines
+	// format. Every title is one JSON object with nested pages and names.
+	JSONL
+	// SQLite saves data to htindex.sqlite database with normalized tables
+	// of titles, pages, name-strings, occurrences and errors.
+	SQLite
+)
+
+func (f Format) String() string {
+	formats := [...]string{"csv", "parquet", "jsonl", "sqlite"}
+	return formats[f]
+}
+
+// NewFormat takes a string and returns matching Format, or an error if such
+// format is not supported.
+func NewFormat(s string) (Format, error) {
+	for _, f := range []Format{CSV, Parquet, JSONL, SQLite} {
+		if f.String() == s {
+			return f, nil
+		}
+	}
+	return CSV, fmt.Errorf("unknown output format '%s'", s)
+}
@@ -0,0 +1,120 @@
+package htindex
+
+import (
+	"regexp"
+	"strings"
+)
+
+// abbrGenus matches names that start with an abbreviated genus, like
+// 'Q. alba' or 'Qu. robur'.
+var abbrGenus = regexp.MustCompile(`^([A-Z][a-z]{0,2})\. (.+)$`)
+
+// expansion is a full form of a name with an abbreviated genus.
+type expansion struct {
+	// name is the name with the full genus.
+	name string
+	// confidence is a class of reliability of the expansion.
+	confidence string
+}
+
+// Classes of confidence of an expanded genus.
+const (
+	// expansionHigh means that the epithet was mentioned earlier only with
+	// the chosen genus.
+	expansionHigh = "high"
+	// expansionMedium means that the chosen genus is the only matching one,
+	// or that several matching genera were mentioned with the epithet.
+	expansionMedium = "medium"
+	// expansionLow means that several genera match the abbreviation and
+	// none of them was mentioned with the epithet.
+	expansionLow = "low"
+)
+
+// generaIndex keeps genera mentioned in a title in full.
+type generaIndex struct {
+	// count is the number of processed mentions.
+	count int
+	// genera contain the last mention of every genus.
+	genera map[string]int
+	// epithets contain the last mention of every genus with an epithet.
+	epithets map[string]map[string]int
+}
+
+// expandGenera resolves abbreviated genera of names in a title using
+// genera mentioned earlier in full. Pages are processed in the order of
+// their IDs, so only previous mentions are used. If several genera match
+// an abbreviation, the one mentioned with the same epithet wins, otherwise
+// the most recent one.
+func (t *title) expandGenera() {
+	idx := generaIndex{
+		genera:   make(map[string]int),
+		epithets: make(map[string]map[string]int),
+	}
+	for i := range t.pages {
+		p := &t.pages[i]
+		p.expansions = make([]expansion, len(p.res.Names))
+		for j, n := range p.res.Names {
+			if m := abbrGenus.FindStringSubmatch(n.Name); m != nil {
+				p.expansions[j] = idx.expand(m[1], m[2])
+				continue
+			}
+			if p.parsed[j].Cardinality > 1 {
+				idx.add(strings.Fields(p.parsed[j].Canonical))
+			}
+		}
+	}
+}
+
+// add registers a full mention of a genus and its epithet.
+func (idx *generaIndex) add(words []string) {
+	idx.count++
+	genus, epithet := words[0], words[1]
+	idx.genera[genus] = idx.count
+	if idx.epithets[epithet] == nil {
+		idx.epithets[epithet] = make(map[string]int)
+	}
+	idx.epithets[epithet][genus] = idx.count
+}
+
+// expand finds the genus for an abbreviation and returns the expanded
+// name. The rest is the name without the abbreviated genus. If no genus
+// matches, or the rest has no words, the expansion is empty.
+func (idx *generaIndex) expand(abbr, rest string) expansion {
+	words := strings.Fields(rest)
+	if len(words) == 0 {
+		return expansion{}
+	}
+	epithet := words[0]
+	var genus, withEpithet string
+	var candidates, withEpithetNum int
+	for g, last := range idx.genera {
+		if !strings.HasPrefix(g, abbr) {
+			continue
+		}
+		candidates++
+		if genus == "" || last > idx.genera[genus] {
+			genus = g
+		}
+		if e, ok := idx.epithets[epithet][g]; ok {
+			withEpithetNum++
+			if withEpithet == "" || e > idx.epithets[epithet][withEpithet] {
+				withEpithet = g
+			}
+		}
+	}
+	res := expansion{confidence: expansionLow}
+	switch {
+	case candidates == 0:
+		return expansion{}
+	case withEpithetNum == 1:
+		genus = withEpithet
+		res.confidence = expansionHigh
+	case withEpithetNum > 1:
+		genus = withEpithet
+		res.confidence = expansionMedium
+	case candidates == 1:
+		res.confidence = expansionMedium
+	}
+	res.name = genus + " " + rest
+	return res
+}
@@ -3,7 +3,7 @@ module github.com/gnames/htindex
 go 1.13
 
 require (
-	github.com/abadojack/whatlanggo v1.0.1 // indirect
+	github.com/abadojack/whatlanggo v1.0.1
 	github.com/gnames/gnfinder v0.9.2-0.20200306200412-bfc655c5b708
 	github.com/json-iterator/go v1.1.7 // indirect
 	github.com/mitchellh/go-homedir v1.1.0
@@ -11,7 +11,10 @@ require (
 	github.com/onsi/gomega v1.7.0
 	github.com/spf13/cobra v0.0.5
 	github.com/spf13/viper v1.4.0
+	github.com/xitongsys/parquet-go v1.5.1
+	github.com/xitongsys/parquet-go-source v0.0.0-20190524061010-2b72cbee77d5
 	gitlab.com/gogna/gnparser v0.10.0
 	golang.org/x/lint v0.0.0-20190909230951-414d861bb4ac // indirect
-	golang.org/x/tools v0.0.0-20190911022129-16c5e0f7d110 // indirect
+	golang.org/x/text v0.3.3
+	modernc.org/sqlite v1.10.6
 )
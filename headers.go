@@ -0,0 +1,148 @@
+package htindex
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	// headerMinPages is the minimal number of pages that start or end with
+	// the same line, so the line is considered a running header or footer.
+	headerMinPages = 3
+	// headerPagesRatio sets the minimal share of pages of a title that start
+	// or end with a running header or footer. For example, 10 means that
+	// every tenth page of the title has to repeat the line.
+	headerPagesRatio = 10
+)
+
+// lineRange is a range of runes of a line in a page text.
+type lineRange struct {
+	start, end int
+}
+
+// runningHeaders finds running headers and footers of pages of a title. A
+// header is the first non-empty line of a page, if many pages start with the
+// same line: at least headerMinPages, and at least every headerPagesRatio
+// page of the title. Footers are found the same way at the ends of pages.
+// Lines are compared by their words only, so page numbers do not matter,
+// and lines made only of page numbers are found as well. It returns ranges
+// of header and footer lines of every page.
+func runningHeaders(texts [][]rune) [][]lineRange {
+	tops := make([]lineRange, len(texts))
+	bottoms := make([]lineRange, len(texts))
+	topKeys := make([]string, len(texts))
+	bottomKeys := make([]string, len(texts))
+	topCount := make(map[string]int)
+	bottomCount := make(map[string]int)
+	for i, text := range texts {
+		var ok bool
+		tops[i], bottoms[i], ok = edgeLines(text)
+		if !ok {
+			continue
+		}
+		topKeys[i] = headerKey(text[tops[i].start:tops[i].end])
+		topCount[topKeys[i]]++
+		if bottoms[i] == tops[i] {
+			continue
+		}
+		bottomKeys[i] = headerKey(text[bottoms[i].start:bottoms[i].end])
+		bottomCount[bottomKeys[i]]++
+	}
+
+	minPages := len(texts) / headerPagesRatio
+	if minPages < headerMinPages {
+		minPages = headerMinPages
+	}
+	res := make([][]lineRange, len(texts))
+	for i := range texts {
+		if tops[i].end == 0 {
+			continue
+		}
+		if topCount[topKeys[i]] >= minPages {
+			res[i] = append(res[i], tops[i])
+		}
+		if bottoms[i] != tops[i] && bottomCount[bottomKeys[i]] >= minPages {
+			res[i] = append(res[i], bottoms[i])
+		}
+	}
+	return res
+}
+
+// edgeLines returns the first and the last non-empty lines of a text. It
+// returns false if all lines are empty.
+func edgeLines(text []rune) (lineRange, lineRange, bool) {
+	var lines []lineRange
+	var start int
+	for i := 0; i <= len(text); i++ {
+		if i < len(text) && text[i] != '\n' {
+			continue
+		}
+		if !isBlank(text[start:i]) {
+			lines = append(lines, lineRange{start: start, end: i})
+		}
+		start = i + 1
+	}
+	if len(lines) == 0 {
+		return lineRange{}, lineRange{}, false
+	}
+	return lines[0], lines[len(lines)-1], true
+}
+
+// isBlank is true if a line contains only spaces.
+func isBlank(line []rune) bool {
+	for _, r := range line {
+		if !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// headerKey converts a line to lowercase words made of letters, so lines
+// that differ only by page numbers or punctuation have the same key.
+func headerKey(line []rune) string {
+	words := strings.FieldsFunc(string(line), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	return strings.ToLower(strings.Join(words, " "))
+}
+
+// detectHeaders is true if settings need running headers and footers of
+// pages.
+func (hti *HTindex) detectHeaders() bool {
+	return hti.FlagHeaderNames || hti.DropHeaderNames ||
+		hti.Normalization&NormHeaders != 0
+}
+
+// inLines is true if a position is inside of one of the lines.
+func inLines(lines []lineRange, pos int) bool {
+	for _, l := range lines {
+		if pos >= l.start && pos < l.end {
+			return true
+		}
+	}
+	return false
+}
+
+// dropHeaderNames removes names that start in running headers or footers
+// of the page.
+func (p *page) dropHeaderNames() {
+	if len(p.headers) == 0 {
+		return
+	}
+	names := p.res.Names[:0]
+	var ends []string
+	if p.endPages != nil {
+		ends = p.endPages[:0]
+	}
+	for i, n := range p.res.Names {
+		if inLines(p.headers, n.OffsetStart) {
+			continue
+		}
+		names = append(names, n)
+		if p.endPages != nil {
+			ends = append(ends, p.endPages[i])
+		}
+	}
+	p.res.Names, p.endPages = names, ends
+}
@@ -6,6 +6,9 @@ import (
 	"runtime"
 
 	"github.com/gnames/gnfinder/dict"
+	"github.com/gnames/gnfinder/lang"
+	"github.com/gnames/htindex/checklist"
+	"github.com/gnames/htindex/verifier"
 )
 
 // HTindex detects occurences of scientific names in Hathi Trust data.
@@ -27,6 +30,83 @@ type HTindex struct {
 	// ProgressNum determines how many titles should be processed for
 	// a progress report.
 	ProgressNum int
+	// Resume allows to continue a run that was interrupted. Titles that are
+	// recorded in the checkpoint journal are skipped, and output files are
+	// appended instead of being created anew.
+	Resume bool
+	// PreviousPath is a directory with the output of a previous run. When it
+	// is set, name-finding runs only for new or changed titles, and results
+	// of unchanged titles are copied from the previous output.
+	PreviousPath string
+	// Walk is true when titles are discovered by traversing pairtree
+	// directories of namespaces in RootPrefix instead of reading InputPath.
+	Walk bool
+	// Namespaces limits the traversal of RootPrefix to given namespaces.
+	// If it is empty, all namespaces are traversed.
+	Namespaces []string
+	// ExcludeNamespaces contains namespaces that are ignored during the
+	// traversal of RootPrefix.
+	ExcludeNamespaces []string
+	// Format sets the format of the output files.
+	Format Format
+	// RowGroupSize sets the size of row groups in megabytes for Parquet
+	// output.
+	RowGroupSize int
+	// Sink receives titles, names and errors instead of the output for
+	// the Format. It allows to save results to a custom storage.
+	Sink Sink
+	// LangDetection sets how languages of texts are detected. Detected
+	// languages select Bayes models of gnfinder.
+	LangDetection LangDetection
+	// Language is the ISO 639-3 code of a language used for all texts. If
+	// it is set, languages are not detected.
+	Language string
+	// Bayes is true when Bayes algorithms are used for name-finding in
+	// addition to heuristic ones.
+	Bayes bool
+	// BayesOddsThreshold sets the minimal odds for name-candidates found
+	// only by Bayes algorithms.
+	BayesOddsThreshold float64
+	// NoHeuristic is true when names found by heuristic algorithms are
+	// dropped, if their Bayes odds are below BayesOddsThreshold.
+	NoHeuristic bool
+	// MinOdds sets the minimal odds of names in the output. Names with
+	// smaller odds are dropped.
+	MinOdds float64
+	// MinPageQuality sets the minimal ratio of dictionary words on a page.
+	// Name-finding is skipped for pages of lower quality.
+	MinPageQuality float64
+	// Stream is true when pages of a title are joined into a continuous
+	// text before name-finding, so names split by page breaks and
+	// hyphenation are found. Pages with different languages are not
+	// joined.
+	Stream bool
+	// Normalization sets steps of OCR text cleaning before name-finding.
+	// Offsets of names refer to original texts of pages.
+	Normalization Normalization
+	// FlagHeaderNames is true when running headers and footers of pages are
+	// detected, and names found in them are flagged.
+	FlagHeaderNames bool
+	// DropHeaderNames is true when names found in running headers and
+	// footers of pages are dropped.
+	DropHeaderNames bool
+	// METS is true when METS files of titles are read to get sequence
+	// numbers and printed page numbers of pages, and to validate texts of
+	// pages against their checksums.
+	METS bool
+	// ChecklistPath is a path to a local reference checklist. If it is
+	// set, found names are verified against the checklist.
+	ChecklistPath string
+	// VerifierURL is the address of a remote name-resolution service. If it
+	// is set, found names are verified by the service.
+	VerifierURL string
+	// Verifier verifies found names. It is created from ChecklistPath or
+	// VerifierURL, or can be given directly. If it is nil, names are not
+	// verified.
+	Verifier verifier.Verifier
+	// StatsTop sets the number of the most frequent names in the statistics
+	// report.
+	StatsTop int
 }
 
 // Option sets the time for all options received during creation of new instance
@@ -58,6 +138,209 @@ func OptProgressNum(i int) Option {
 
 }
 
+// OptResume sets a mode where an interrupted run continues from the last
+// title that was completely written to the output.
+func OptResume(b bool) Option {
+	return func(h *HTindex) {
+		h.Resume = b
+	}
+}
+
+// OptPrevious sets incremental mode. It takes a path to the output
+// directory of a previous run. Titles with the same SHA256 as in the
+// previous run are not processed again, their results are copied instead.
+// Titles that disappeared from the input are saved to removed.csv file.
+func OptPrevious(s string) Option {
+	return func(h *HTindex) {
+		h.PreviousPath = s
+	}
+}
+
+// OptWalk sets discovery of titles by traversing pairtree directories of
+// HathiTrust namespaces (for example mdp/pairtree_root) located in the root
+// directory. In this mode the input file is ignored.
+func OptWalk(b bool) Option {
+	return func(h *HTindex) {
+		h.Walk = b
+	}
+}
+
+// OptNamespaces sets namespaces (mdp, uc2, miun...) that are traversed when
+// titles are discovered from the root directory.
+func OptNamespaces(ns []string) Option {
+	return func(h *HTindex) {
+		h.Namespaces = ns
+	}
+}
+
+// OptExcludeNamespaces sets namespaces that are skipped when titles are
+// discovered from the root directory.
+func OptExcludeNamespaces(ns []string) Option {
+	return func(h *HTindex) {
+		h.ExcludeNamespaces = ns
+	}
+}
+
+// OptFormat sets the format of the output files. CSV is the default.
+func OptFormat(f Format) Option {
+	return func(h *HTindex) {
+		h.Format = f
+	}
+}
+
+// OptRowGroupSize sets the size of row groups in megabytes for Parquet
+// output. Bigger row groups make reading faster, but need more memory
+// during writing.
+func OptRowGroupSize(i int) Option {
+	return func(h *HTindex) {
+		h.RowGroupSize = i
+	}
+}
+
+// OptSink sets a custom Sink for the output. If it is given, Format is
+// ignored. Resume and incremental modes do not work with custom sinks.
+func OptSink(s Sink) Option {
+	return func(h *HTindex) {
+		h.Sink = s
+	}
+}
+
+// OptLangDetection sets detection of languages per title, per page, or
+// turns it off. By default one language is detected for a title.
+func OptLangDetection(ld LangDetection) Option {
+	return func(h *HTindex) {
+		h.LangDetection = ld
+	}
+}
+
+// OptLanguage sets one language for all texts. It takes ISO 639-3 code of
+// a language supported by gnfinder (for example 'eng' or 'deu'). When
+// the language is set, language detection is turned off.
+func OptLanguage(s string) Option {
+	return func(h *HTindex) {
+		h.Language = s
+	}
+}
+
+// OptBayes turns Bayes name-finding on or off. It is on by default.
+func OptBayes(b bool) Option {
+	return func(h *HTindex) {
+		h.Bayes = b
+	}
+}
+
+// OptBayesOddsThreshold sets the minimal odds for name-candidates found by
+// Bayes algorithms. Candidates with smaller odds are dropped. A higher
+// threshold gives fewer false positives, but misses more names.
+func OptBayesOddsThreshold(f float64) Option {
+	return func(h *HTindex) {
+		h.BayesOddsThreshold = f
+	}
+}
+
+// OptNoHeuristic drops names found by heuristic algorithms, unless Bayes
+// odds of such names reach BayesOddsThreshold. It needs Bayes name-finding.
+func OptNoHeuristic(b bool) Option {
+	return func(h *HTindex) {
+		h.NoHeuristic = b
+	}
+}
+
+// OptMinOdds sets the minimal odds of names saved to the output. Without
+// Bayes name-finding all names have zero odds, so this option needs Bayes.
+func OptMinOdds(f float64) Option {
+	return func(h *HTindex) {
+		h.MinOdds = f
+	}
+}
+
+// OptMinPageQuality sets the minimal quality of a page for name-finding.
+// The quality is the ratio of dictionary words among words of the page,
+// pages with bad OCR have low ratios and give many false positives.
+func OptMinPageQuality(f float64) Option {
+	return func(h *HTindex) {
+		h.MinPageQuality = f
+	}
+}
+
+// OptStream sets a mode where every title is processed as a continuous
+// text with page breaks and hyphenation removed. Offsets of names still
+// refer to texts of their pages.
+func OptStream(b bool) Option {
+	return func(h *HTindex) {
+		h.Stream = b
+	}
+}
+
+// OptNormalization sets steps of cleaning of texts before name-finding,
+// for example Unicode normalization or removal of running headers.
+func OptNormalization(n Normalization) Option {
+	return func(h *HTindex) {
+		h.Normalization = n
+	}
+}
+
+// OptFlagHeaderNames sets detection of running headers and footers. Names
+// found in them get the InHeader flag. Detection needs additional memory
+// and time, so it is off by default.
+func OptFlagHeaderNames(b bool) Option {
+	return func(h *HTindex) {
+		h.FlagHeaderNames = b
+	}
+}
+
+// OptDropHeaderNames sets removal of names found in running headers and
+// footers. Such names are repeated on many pages and inflate numbers of
+// occurrences.
+func OptDropHeaderNames(b bool) Option {
+	return func(h *HTindex) {
+		h.DropHeaderNames = b
+	}
+}
+
+// OptMETS sets reading of METS files that HathiTrust keeps next to zip
+// files of titles. Printed page numbers from METS files are added to the
+// output, and texts of pages with checksums that do not match METS files
+// are reported as errors.
+func OptMETS(b bool) Option {
+	return func(h *HTindex) {
+		h.METS = b
+	}
+}
+
+// OptChecklist sets a path to a local reference checklist, a tab-separated
+// file or a Darwin Core Archive. Found names are verified against the
+// checklist without network access.
+func OptChecklist(s string) Option {
+	return func(h *HTindex) {
+		h.ChecklistPath = s
+	}
+}
+
+// OptVerifierURL sets the address of a remote name-resolution service with
+// GNverifier API for verification of found names.
+func OptVerifierURL(s string) Option {
+	return func(h *HTindex) {
+		h.VerifierURL = s
+	}
+}
+
+// OptVerifier sets a custom Verifier of found names. If it is given,
+// ChecklistPath and VerifierURL are ignored.
+func OptVerifier(v verifier.Verifier) Option {
+	return func(h *HTindex) {
+		h.Verifier = v
+	}
+}
+
+// OptStatsTop sets the number of the most frequent names in the statistics
+// report (stats.json and stats.txt) created at the end of a run.
+func OptStatsTop(i int) Option {
+	return func(h *HTindex) {
+		h.StatsTop = i
+	}
+}
+
 // OptRoot sets the prefix of the path to zipped titles. It wil be concatenated
 // with a path provided in the input file to receive complete absolute path.
 func OptRoot(s string) Option {
@@ -88,17 +371,65 @@ func OptOutput(s string) Option {
 func NewHTindex(opts ...Option) (*HTindex, error) {
 
 	hti := &HTindex{
-		Dict:        dict.LoadDictionary(),
-		ProgressNum: 0,
-		JobsNum:     runtime.NumCPU(),
+		Dict:               dict.LoadDictionary(),
+		ProgressNum:        0,
+		JobsNum:            runtime.NumCPU(),
+		RowGroupSize:       128,
+		Bayes:              true,
+		BayesOddsThreshold: 100,
+		StatsTop:           20,
 	}
 	for _, opt := range opts {
 		opt(hti)
 	}
+	if err := hti.checkEngine(); err != nil {
+		return hti, err
+	}
+	if err := hti.setVerifier(); err != nil {
+		return hti, err
+	}
 	err := hti.setOutputDir()
 	return hti, err
 }
 
+// checkEngine validates settings of name-finding.
+func (hti *HTindex) checkEngine() error {
+	if hti.Language != "" {
+		if _, err := lang.NewLanguage(hti.Language); err != nil {
+			return err
+		}
+	}
+	if hti.NoHeuristic && !hti.Bayes {
+		return fmt.Errorf("heuristic name-finding cannot be disabled without Bayes")
+	}
+	if hti.MinOdds > 0 && !hti.Bayes {
+		return fmt.Errorf("minimal odds cannot be used without Bayes")
+	}
+	return nil
+}
+
+// setVerifier creates a Verifier from the local checklist or from the
+// address of a remote service, unless the Verifier is already given.
+func (hti *HTindex) setVerifier() error {
+	if hti.Verifier != nil {
+		return nil
+	}
+	if hti.ChecklistPath != "" && hti.VerifierURL != "" {
+		return fmt.Errorf("checklist and verifier URL cannot be used together")
+	}
+	if hti.VerifierURL != "" {
+		hti.Verifier = verifier.NewHTTP(hti.VerifierURL)
+	}
+	if hti.ChecklistPath != "" {
+		c, err := checklist.Load(hti.ChecklistPath)
+		if err != nil {
+			return err
+		}
+		hti.Verifier = c
+	}
+	return nil
+}
+
 func (hti *HTindex) setOutputDir() error {
 	path, err := os.Stat(hti.OutputPath)
 	if os.IsNotExist(err) {
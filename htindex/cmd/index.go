@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gnames/htindex/nameindex"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// indexCmd creates an inverted index of names from the results of a run.
+var indexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "creates an index of canonical names from results.csv",
+	Long: `Reads results.csv from the output directory and creates names.idx
+	file that connects canonical forms of names to titles and pages where
+	they were found, and titles.idx file that connects titles to their
+	names. The index is used by the lookup and serve commands.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := outputDir(cmd)
+		num, err := nameindex.Build(dir)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("Indexed %d names in %s\n", num, dir)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(indexCmd)
+	indexCmd.Flags().StringP("output", "o", "", "path to the output directory")
+}
+
+// outputDir returns the output directory from the flag of a command, or
+// from the configuration file.
+func outputDir(cmd *cobra.Command) string {
+	dir, err := cmd.Flags().GetString("output")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if dir == "" {
+		dir = viper.GetString("Output")
+	}
+	if dir == "" {
+		fmt.Println("output directory is not set")
+		os.Exit(1)
+	}
+	return dir
+}
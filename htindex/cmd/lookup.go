@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gnames/htindex/nameindex"
+	"github.com/spf13/cobra"
+)
+
+// lookupCmd finds titles and pages that mention a name.
+var lookupCmd = &cobra.Command{
+	Use:   "lookup name",
+	Short: "finds titles and pages that mention a name",
+	Long: `Searches the index created by the index command for the canonical
+	form of a name, and prints titles and pages where the name was found.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := strings.Join(args, " ")
+		e, err := nameindex.Lookup(outputDir(cmd), name)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if e == nil {
+			fmt.Printf("Name '%s' is not found\n", name)
+			os.Exit(1)
+		}
+		fmt.Printf("%s: %d occurrences in %d titles\n", e.Name, e.Count,
+			len(e.Titles))
+		for _, t := range e.Titles {
+			pages := make([]string, len(t.Pages))
+			for i, p := range t.Pages {
+				pages[i] = fmt.Sprintf("%s(%d)", p.ID, p.Count)
+			}
+			fmt.Printf("%s\t%d\t%s\n", t.ID, t.Count, strings.Join(pages, ","))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lookupCmd)
+	lookupCmd.Flags().StringP("output", "o", "", "path to the output directory")
+}
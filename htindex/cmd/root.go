@@ -21,12 +21,34 @@ var (
 // config purpose is to achieve automatic import of data from the
 // configuration file, if it exists.
 type config struct {
-	Root        string
-	Input       string
-	Output      string
-	Jobs        int
-	WordsAround int
-	ProgressNum int
+	Root              string
+	Input             string
+	Output            string
+	Jobs              int
+	WordsAround       int
+	ProgressNum       int
+	Resume            bool
+	Previous          string
+	Walk              bool
+	Namespaces        []string
+	ExcludeNamespaces []string
+	Format            string
+	RowGroupSize      int
+	StatsTop          int
+	LangDetection     string
+	Language          string
+	NoBayes           bool
+	OddsThreshold     float64
+	NoHeuristic       bool
+	MinOdds           float64
+	Checklist         string
+	VerifierURL       string
+	MinPageQuality    float64
+	Stream            bool
+	Normalize         []string
+	FlagHeaderNames   bool
+	DropHeaderNames   bool
+	METS              bool
 }
 
 // rootCmd represents the base command when called without any subcommands
@@ -72,6 +94,28 @@ func init() {
 	rootCmd.Flags().IntP("jobs", "j", 0, "number of workers (jobs)")
 	rootCmd.Flags().IntP("words-around", "w", 0, "keep this number of words before and after a name")
 	rootCmd.Flags().IntP("progress", "p", 0, "number of titles in progress report")
+	rootCmd.Flags().BoolP("resume", "R", false, "continue an interrupted run")
+	rootCmd.Flags().StringP("previous", "P", "", "path to the output of a previous run for incremental indexing")
+	rootCmd.Flags().BoolP("walk", "W", false, "find titles in pairtree directories of the root path instead of the input file")
+	rootCmd.Flags().StringSliceP("namespaces", "n", nil, "walk only these namespaces (comma separated)")
+	rootCmd.Flags().StringSliceP("exclude-namespaces", "x", nil, "do not walk these namespaces (comma separated)")
+	rootCmd.Flags().StringP("format", "f", "", "output format: csv, parquet, jsonl, sqlite")
+	rootCmd.Flags().IntP("row-group-size", "g", 0, "size of Parquet row groups in megabytes")
+	rootCmd.Flags().IntP("stats-top", "t", 0, "number of the most frequent names in the statistics report")
+	rootCmd.Flags().StringP("lang-detection", "l", "", "language detection: title, page, none")
+	rootCmd.Flags().StringP("language", "L", "", "ISO 639-3 code of the language of all texts (eng, deu)")
+	rootCmd.Flags().BoolP("no-bayes", "b", false, "do not use Bayes algorithms for name-finding")
+	rootCmd.Flags().Float64P("odds-threshold", "d", 0, "minimal odds for names found by Bayes algorithms")
+	rootCmd.Flags().BoolP("no-heuristic", "H", false, "drop heuristic names that have low Bayes odds")
+	rootCmd.Flags().Float64P("min-odds", "m", 0, "minimal odds of names in the output")
+	rootCmd.Flags().StringP("checklist", "c", "", "path to a local checklist (TSV or DwC-A) for verification of names")
+	rootCmd.Flags().StringP("verifier-url", "V", "", "URL of a name verification service")
+	rootCmd.Flags().Float64P("min-page-quality", "q", 0, "minimal ratio of dictionary words on a page for name-finding")
+	rootCmd.Flags().BoolP("stream", "s", false, "find names in titles joined into continuous texts, pages with different languages are not joined")
+	rootCmd.Flags().StringSliceP("normalize", "N", nil, "steps of text cleaning: unicode, ligatures, headers, hyphens or all (comma separated)")
+	rootCmd.Flags().BoolP("flag-header-names", "F", false, "flag names found in running headers and footers")
+	rootCmd.Flags().BoolP("drop-header-names", "D", false, "drop names found in running headers and footers")
+	rootCmd.Flags().BoolP("mets", "M", false, "read METS files for page labels and checksums")
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -142,6 +186,84 @@ func getOpts() []htindex.Option {
 	if cfg.ProgressNum > 0 {
 		opts = append(opts, htindex.OptProgressNum(cfg.ProgressNum))
 	}
+	if cfg.Resume {
+		opts = append(opts, htindex.OptResume(true))
+	}
+	if cfg.Previous != "" {
+		opts = append(opts, htindex.OptPrevious(cfg.Previous))
+	}
+	if cfg.Walk {
+		opts = append(opts, htindex.OptWalk(true))
+	}
+	if len(cfg.Namespaces) > 0 {
+		opts = append(opts, htindex.OptNamespaces(cfg.Namespaces))
+	}
+	if len(cfg.ExcludeNamespaces) > 0 {
+		opts = append(opts, htindex.OptExcludeNamespaces(cfg.ExcludeNamespaces))
+	}
+	if cfg.Format != "" {
+		f, err := htindex.NewFormat(cfg.Format)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts = append(opts, htindex.OptFormat(f))
+	}
+	if cfg.RowGroupSize > 0 {
+		opts = append(opts, htindex.OptRowGroupSize(cfg.RowGroupSize))
+	}
+	if cfg.StatsTop > 0 {
+		opts = append(opts, htindex.OptStatsTop(cfg.StatsTop))
+	}
+	if cfg.LangDetection != "" {
+		ld, err := htindex.NewLangDetection(cfg.LangDetection)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts = append(opts, htindex.OptLangDetection(ld))
+	}
+	if cfg.Language != "" {
+		opts = append(opts, htindex.OptLanguage(cfg.Language))
+	}
+	if cfg.NoBayes {
+		opts = append(opts, htindex.OptBayes(false))
+	}
+	if cfg.OddsThreshold > 0 {
+		opts = append(opts, htindex.OptBayesOddsThreshold(cfg.OddsThreshold))
+	}
+	if cfg.NoHeuristic {
+		opts = append(opts, htindex.OptNoHeuristic(true))
+	}
+	if cfg.MinOdds > 0 {
+		opts = append(opts, htindex.OptMinOdds(cfg.MinOdds))
+	}
+	if cfg.Checklist != "" {
+		opts = append(opts, htindex.OptChecklist(cfg.Checklist))
+	}
+	if cfg.VerifierURL != "" {
+		opts = append(opts, htindex.OptVerifierURL(cfg.VerifierURL))
+	}
+	if cfg.MinPageQuality > 0 {
+		opts = append(opts, htindex.OptMinPageQuality(cfg.MinPageQuality))
+	}
+	if cfg.Stream {
+		opts = append(opts, htindex.OptStream(true))
+	}
+	if len(cfg.Normalize) > 0 {
+		n, err := htindex.NewNormalization(cfg.Normalize)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts = append(opts, htindex.OptNormalization(n))
+	}
+	if cfg.FlagHeaderNames {
+		opts = append(opts, htindex.OptFlagHeaderNames(true))
+	}
+	if cfg.DropHeaderNames {
+		opts = append(opts, htindex.OptDropHeaderNames(true))
+	}
+	if cfg.METS {
+		opts = append(opts, htindex.OptMETS(true))
+	}
 	return opts
 }
 
@@ -195,5 +317,193 @@ func getFlags(opts []htindex.Option, cmd *cobra.Command) []htindex.Option {
 	if progress > 0 {
 		opts = append(opts, htindex.OptProgressNum(progress))
 	}
+	resume, err := cmd.Flags().GetBool("resume")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if resume {
+		opts = append(opts, htindex.OptResume(true))
+	}
+	previous, err := cmd.Flags().GetString("previous")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if previous != "" {
+		opts = append(opts, htindex.OptPrevious(previous))
+	}
+	walk, err := cmd.Flags().GetBool("walk")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if walk {
+		opts = append(opts, htindex.OptWalk(true))
+	}
+	nss, err := cmd.Flags().GetStringSlice("namespaces")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if len(nss) > 0 {
+		opts = append(opts, htindex.OptNamespaces(nss))
+	}
+	exclude, err := cmd.Flags().GetStringSlice("exclude-namespaces")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if len(exclude) > 0 {
+		opts = append(opts, htindex.OptExcludeNamespaces(exclude))
+	}
+	format, err := cmd.Flags().GetString("format")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if format != "" {
+		f, err := htindex.NewFormat(format)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts = append(opts, htindex.OptFormat(f))
+	}
+	rowGroup, err := cmd.Flags().GetInt("row-group-size")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if rowGroup > 0 {
+		opts = append(opts, htindex.OptRowGroupSize(rowGroup))
+	}
+	top, err := cmd.Flags().GetInt("stats-top")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if top > 0 {
+		opts = append(opts, htindex.OptStatsTop(top))
+	}
+	langDetection, err := cmd.Flags().GetString("lang-detection")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if langDetection != "" {
+		ld, err := htindex.NewLangDetection(langDetection)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts = append(opts, htindex.OptLangDetection(ld))
+	}
+	language, err := cmd.Flags().GetString("language")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if language != "" {
+		opts = append(opts, htindex.OptLanguage(language))
+	}
+	noBayes, err := cmd.Flags().GetBool("no-bayes")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if noBayes {
+		opts = append(opts, htindex.OptBayes(false))
+	}
+	odds, err := cmd.Flags().GetFloat64("odds-threshold")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if odds > 0 {
+		opts = append(opts, htindex.OptBayesOddsThreshold(odds))
+	}
+	noHeuristic, err := cmd.Flags().GetBool("no-heuristic")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if noHeuristic {
+		opts = append(opts, htindex.OptNoHeuristic(true))
+	}
+	minOdds, err := cmd.Flags().GetFloat64("min-odds")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if minOdds > 0 {
+		opts = append(opts, htindex.OptMinOdds(minOdds))
+	}
+	checklist, err := cmd.Flags().GetString("checklist")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if checklist != "" {
+		opts = append(opts, htindex.OptChecklist(checklist))
+	}
+	verifierURL, err := cmd.Flags().GetString("verifier-url")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if verifierURL != "" {
+		opts = append(opts, htindex.OptVerifierURL(verifierURL))
+	}
+	quality, err := cmd.Flags().GetFloat64("min-page-quality")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if quality > 0 {
+		opts = append(opts, htindex.OptMinPageQuality(quality))
+	}
+	stream, err := cmd.Flags().GetBool("stream")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if stream {
+		opts = append(opts, htindex.OptStream(true))
+	}
+	steps, err := cmd.Flags().GetStringSlice("normalize")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if len(steps) > 0 {
+		n, err := htindex.NewNormalization(steps)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts = append(opts, htindex.OptNormalization(n))
+	}
+	flagHeaders, err := cmd.Flags().GetBool("flag-header-names")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if flagHeaders {
+		opts = append(opts, htindex.OptFlagHeaderNames(true))
+	}
+	dropHeaders, err := cmd.Flags().GetBool("drop-header-names")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if dropHeaders {
+		opts = append(opts, htindex.OptDropHeaderNames(true))
+	}
+	useMETS, err := cmd.Flags().GetBool("mets")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if useMETS {
+		opts = append(opts, htindex.OptMETS(true))
+	}
 	return opts
 }
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gnames/htindex/server"
+	"github.com/spf13/cobra"
+)
+
+// serveCmd starts a REST API over the output directory.
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "starts a REST API to query names and titles of the output",
+	Long: `Starts an HTTP server that answers queries about names and titles
+	using titles.csv, names.idx and titles.idx files of the output
+	directory. The index has to be created by the index command first.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		host, err := cmd.Flags().GetString("host")
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		port, err := cmd.Flags().GetInt("port")
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		s, err := server.New(outputDir(cmd))
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		addr := net.JoinHostPort(host, strconv.Itoa(port))
+		fmt.Printf("Serving API at http://%s/api/v1\n", addr)
+		log.Fatal(http.ListenAndServe(addr, s))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().StringP("output", "o", "", "path to the output directory")
+	serveCmd.Flags().String("host", "127.0.0.1", "address of the server, use 0.0.0.0 to accept remote connections")
+	serveCmd.Flags().IntP("port", "p", 8080, "port of the server")
+}
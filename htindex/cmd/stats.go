@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gnames/htindex"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// statsCmd creates a statistics report from an existing output.
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "creates a statistics report about names of the output",
+	Long: `Reads titles.csv and results.csv from the output directory, saves
+	statistics about found names to stats.json and stats.txt files, and
+	prints the text version of the report.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		top, err := cmd.Flags().GetInt("stats-top")
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if top == 0 {
+			top = viper.GetInt("StatsTop")
+		}
+		if top == 0 {
+			top = 20
+		}
+		dir := outputDir(cmd)
+		stats, err := htindex.NewStatsFromOutput(dir, top)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if err = stats.Save(dir); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if err = stats.WriteText(os.Stdout); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+	statsCmd.Flags().StringP("output", "o", "", "path to the output directory")
+	statsCmd.Flags().IntP("stats-top", "t", 0, "number of the most frequent names in the report")
+}
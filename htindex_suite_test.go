@@ -10,7 +10,11 @@ import (
 )
 
 const (
-	testOutput = "/tmp/htindex-test"
+	testOutput        = "/tmp/htindex-test"
+	testOutputResume  = "/tmp/htindex-test-resume"
+	testOutputParquet = "/tmp/htindex-test-parquet"
+	testOutputJSONL   = "/tmp/htindex-test-jsonl"
+	testOutputSQLite  = "/tmp/htindex-test-sqlite"
 )
 
 func TestHtindex(t *testing.T) {
@@ -1,16 +1,29 @@
 package htindex_test
 
 import (
+	"archive/zip"
+	"bufio"
+	"database/sql"
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/reader"
+	_ "modernc.org/sqlite"
 
 	. "github.com/gnames/htindex"
 )
@@ -30,6 +43,18 @@ var _ = Describe("Htindex", func() {
 			Expect(hti.JobsNum).To(Equal(4))
 			Expect(hti.OutputPath).To(Equal(testOutput))
 		})
+
+		It("validates name-finding options", func() {
+			hti, err := NewHTindex(initOpts()...)
+			Expect(err).To(BeNil())
+			Expect(hti.Bayes).To(BeTrue())
+			Expect(hti.BayesOddsThreshold).To(Equal(100.0))
+			_, err = NewHTindex(append(initOpts(), OptLanguage("xyz"))...)
+			Expect(err).ToNot(BeNil())
+			_, err = NewHTindex(append(initOpts(), OptBayes(false),
+				OptNoHeuristic(true))...)
+			Expect(err).ToNot(BeNil())
+		})
 	})
 
 	Describe("Run", func() {
@@ -47,6 +72,641 @@ var _ = Describe("Htindex", func() {
 			os.Stdout = stdout
 		})
 
+		It("parses found names", func() {
+			stdout := os.Stdout
+			os.Stdout, _ = os.Open(os.DevNull)
+			hti, _ := NewHTindex(initOpts()...)
+			Expect(hti.Run()).To(Succeed())
+			data := getTestData(hti.OutputPath)
+			ids := make(map[string]string)
+			var binomials int
+			for _, v := range data {
+				if v.Kind == "Binomial" && v.Canonical != "" {
+					binomials++
+					Expect(v.Cardinality).To(Equal("2"))
+					Expect(v.Quality).ToNot(Equal("0"))
+				}
+				if v.Canonical == "" {
+					Expect(v.Cardinality).To(Equal("0"))
+				}
+				Expect(len(v.NameID)).To(Equal(36))
+				if id, ok := ids[v.NameString]; ok {
+					Expect(v.NameID).To(Equal(id))
+				}
+				ids[v.NameString] = v.NameID
+			}
+			Expect(binomials).To(BeNumerically(">", 0))
+			os.Stdout = stdout
+		})
+
+		It("summarizes names of every title", func() {
+			stdout := os.Stdout
+			os.Stdout, _ = os.Open(os.DevNull)
+			hti, _ := NewHTindex(initOpts()...)
+			Expect(hti.Run()).To(Succeed())
+			data := getTestData(hti.OutputPath)
+			Expect(summaryCount(hti.OutputPath)).To(Equal(resultsCount(data)))
+
+			pages := make(map[string]map[string]struct{})
+			for _, v := range data {
+				k := v.ID + "|" + v.NameString
+				if _, ok := pages[k]; !ok {
+					pages[k] = make(map[string]struct{})
+				}
+				pages[k][v.PageID] = struct{}{}
+			}
+			f, err := os.Open(filepath.Join(hti.OutputPath, "summary.csv"))
+			Expect(err).To(BeNil())
+			defer f.Close()
+			rows, err := csv.NewReader(f).ReadAll()
+			Expect(err).To(BeNil())
+			Expect(rows[0]).To(Equal([]string{"ID", "NameString",
+				"OccurrencesNumber", "FirstPageID", "LastPageID", "PagesNumber",
+				"MaxOdds"}))
+			Expect(len(rows) - 1).To(Equal(len(pages)))
+			for _, row := range rows[1:] {
+				ps := pages[row[0]+"|"+row[1]]
+				Expect(row[5]).To(Equal(strconv.Itoa(len(ps))))
+				Expect(ps).To(HaveKey(row[3]))
+				Expect(ps).To(HaveKey(row[4]))
+				Expect(row[3] <= row[4]).To(BeTrue())
+			}
+			os.Stdout = stdout
+		})
+
+		It("creates statistics report", func() {
+			stdout := os.Stdout
+			os.Stdout, _ = os.Open(os.DevNull)
+			hti, _ := NewHTindex(append(initOpts(), OptStatsTop(3))...)
+			Expect(hti.Run()).To(Succeed())
+			data := getTestData(hti.OutputPath)
+			stats := readStats(hti.OutputPath)
+			Expect(stats.OccurrencesNumber).To(Equal(len(data)))
+			Expect(stats.TitlesNumber).To(Equal(len(readTitleIDs(hti.OutputPath, "titles.csv"))))
+			Expect(len(stats.TopNames)).To(Equal(3))
+			names := make(map[string]int)
+			kinds := make(map[string]int)
+			for _, v := range data {
+				names[v.NameString]++
+				kinds[v.Kind]++
+			}
+			Expect(stats.NameStringsNumber).To(Equal(len(names)))
+			Expect(stats.Kinds).To(Equal(kinds))
+			for _, v := range stats.TopNames {
+				Expect(v.Count).To(Equal(names[v.NameString]))
+			}
+			Expect(stats.TitlesWithoutNames).To(Equal([]string{"yale.39002007302079"}))
+			Expect(stats.Namespaces).To(HaveKey("mdp"))
+			Expect(stats.Namespaces["yale"].OccurrencesNumber).To(Equal(0))
+			nsNames := make(map[string]map[string]struct{})
+			for _, v := range data {
+				ns := strings.Split(v.ID, ".")[0]
+				if nsNames[ns] == nil {
+					nsNames[ns] = make(map[string]struct{})
+				}
+				nsNames[ns][v.NameString] = struct{}{}
+			}
+			for k, v := range stats.Namespaces {
+				Expect(v.NameStringsNumber).To(Equal(len(nsNames[k])))
+			}
+
+			fromOutput, err := NewStatsFromOutput(hti.OutputPath, 3)
+			Expect(err).To(BeNil())
+			Expect(fromOutput).To(Equal(stats))
+			text, err := ioutil.ReadFile(filepath.Join(hti.OutputPath, "stats.txt"))
+			Expect(err).To(BeNil())
+			Expect(string(text)).To(ContainSubstring("yale.39002007302079"))
+			os.Stdout = stdout
+		})
+
+		It("detects languages of titles and pages", func() {
+			stdout := os.Stdout
+			os.Stdout, _ = os.Open(os.DevNull)
+			hti, _ := NewHTindex(initOpts()...)
+			Expect(hti.LangDetection).To(Equal(LangTitle))
+			Expect(hti.Run()).To(Succeed())
+			langs := readTitleLanguages(hti.OutputPath)
+			Expect(langs["mdp.39015027528713"]).To(Equal("eng"))
+			Expect(langs["miun.acl9167.0001.001"]).To(Equal("eng"))
+			for _, v := range getTestData(hti.OutputPath) {
+				Expect(v.Language).To(Equal(langs[v.ID]))
+			}
+
+			hti, _ = NewHTindex(append(initOpts(), OptLangDetection(LangPage))...)
+			Expect(hti.Run()).To(Succeed())
+			langs = readTitleLanguages(hti.OutputPath)
+			Expect(langs["mdp.39015027528713"]).To(Equal("eng"))
+			pageLangs := make(map[string]int)
+			for _, v := range getTestData(hti.OutputPath) {
+				pageLangs[v.Language]++
+			}
+			Expect(pageLangs["eng"]).To(BeNumerically(">", 0))
+
+			hti, _ = NewHTindex(append(initOpts(), OptLangDetection(LangNone))...)
+			Expect(hti.Run()).To(Succeed())
+			for _, v := range readTitleLanguages(hti.OutputPath) {
+				Expect(v).To(Equal(""))
+			}
+			os.Stdout = stdout
+		})
+
+		It("uses name-finding options", func() {
+			stdout := os.Stdout
+			os.Stdout, _ = os.Open(os.DevNull)
+			hti, _ := NewHTindex(initOpts()...)
+			Expect(hti.Run()).To(Succeed())
+			all := getTestData(hti.OutputPath)
+
+			hti, _ = NewHTindex(append(initOpts(), OptNoHeuristic(true))...)
+			Expect(hti.Run()).To(Succeed())
+			data := getTestData(hti.OutputPath)
+			Expect(len(data)).To(BeNumerically("<", len(all)))
+			for _, v := range data {
+				odds, err := strconv.ParseFloat(v.Odds, 64)
+				Expect(err).To(BeNil())
+				Expect(odds).To(BeNumerically(">=", 100))
+			}
+
+			hti, _ = NewHTindex(append(initOpts(), OptBayes(false))...)
+			Expect(hti.Run()).To(Succeed())
+			for _, v := range getTestData(hti.OutputPath) {
+				Expect(v.Kind).ToNot(HavePrefix("Bayes"))
+			}
+
+			hti, _ = NewHTindex(append(initOpts(), OptLanguage("deu"))...)
+			Expect(hti.Run()).To(Succeed())
+			for _, v := range readTitleLanguages(hti.OutputPath) {
+				Expect(v).To(Equal("deu"))
+			}
+			os.Stdout = stdout
+		})
+
+		It("filters names by odds and classifies their confidence", func() {
+			stdout := os.Stdout
+			os.Stdout, _ = os.Open(os.DevNull)
+			hti, _ := NewHTindex(initOpts()...)
+			Expect(hti.Run()).To(Succeed())
+			all := getTestData(hti.OutputPath)
+			classes := make(map[string]int)
+			var fractions bool
+			for _, v := range all {
+				odds, err := strconv.ParseFloat(v.Odds, 64)
+				Expect(err).To(BeNil())
+				if odds != float64(int(odds)) {
+					fractions = true
+				}
+				classes[v.ConfidenceClass]++
+				switch {
+				case odds >= 10000:
+					Expect(v.ConfidenceClass).To(Equal("high"))
+				case odds >= 100:
+					Expect(v.ConfidenceClass).To(Equal("medium"))
+				case odds > 0:
+					Expect(v.ConfidenceClass).To(Equal("low"))
+				}
+			}
+			Expect(fractions).To(BeTrue())
+			Expect(classes["high"]).To(BeNumerically(">", 0))
+			Expect(classes["low"]).To(BeNumerically(">", 0))
+
+			hti, _ = NewHTindex(append(initOpts(), OptMinOdds(10000))...)
+			Expect(hti.Run()).To(Succeed())
+			data := getTestData(hti.OutputPath)
+			Expect(len(data)).To(Equal(classes["high"]))
+			for _, v := range data {
+				Expect(v.ConfidenceClass).To(Equal("high"))
+			}
+
+			hti, _ = NewHTindex(append(initOpts(), OptBayes(false))...)
+			Expect(hti.Run()).To(Succeed())
+			for _, v := range getTestData(hti.OutputPath) {
+				Expect(v.ConfidenceClass).To(Equal("unknown"))
+			}
+			_, err := NewHTindex(append(initOpts(), OptBayes(false),
+				OptMinOdds(1))...)
+			Expect(err).ToNot(BeNil())
+			os.Stdout = stdout
+		})
+
+		It("verifies names against a local checklist", func() {
+			stdout := os.Stdout
+			os.Stdout, _ = os.Open(os.DevNull)
+			hti, _ := NewHTindex(initOpts()...)
+			Expect(hti.Run()).To(Succeed())
+			for _, v := range getTestData(hti.OutputPath) {
+				Expect(v.MatchType).To(Equal(""))
+			}
+
+			path, err := filepath.Abs("./checklist/testdata/checklist.tsv")
+			Expect(err).To(BeNil())
+			hti, err = NewHTindex(append(initOpts(), OptChecklist(path))...)
+			Expect(err).To(BeNil())
+			Expect(hti.Run()).To(Succeed())
+			matches := make(map[string]int)
+			for _, v := range getTestData(hti.OutputPath) {
+				matches[v.MatchType]++
+				Expect(v.DataSource).To(Equal("checklist"))
+				switch v.NameString {
+				case "Crania":
+					Expect(v.MatchType).To(Equal("Canonical"))
+					Expect(v.MatchID).To(Equal("3"))
+					Expect(v.AcceptedName).To(Equal("Crania Retzius, 1781"))
+				case "Leonora":
+					Expect(v.MatchType).To(Equal("NoMatch"))
+					Expect(v.MatchID).To(Equal(""))
+				}
+			}
+			Expect(matches["Canonical"]).To(BeNumerically(">", 0))
+			Expect(matches["NoMatch"]).To(BeNumerically(">", 0))
+
+			_, err = NewHTindex(append(initOpts(), OptChecklist("nofile.tsv"))...)
+			Expect(err).ToNot(BeNil())
+			os.Stdout = stdout
+		})
+
+		It("verifies names with a remote service", func() {
+			stdout := os.Stdout
+			os.Stdout, _ = os.Open(os.DevNull)
+			var requests int
+			srv := httptest.NewServer(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					requests++
+					var req struct {
+						NameStrings []string `json:"nameStrings"`
+					}
+					Expect(json.NewDecoder(r.Body).Decode(&req)).To(Succeed())
+					res := make([]map[string]interface{}, len(req.NameStrings))
+					for i, n := range req.NameStrings {
+						res[i] = map[string]interface{}{"name": n, "matchType": "NoMatch"}
+						if n == "Crania" {
+							res[i]["matchType"] = "Exact"
+							res[i]["bestResult"] = map[string]string{
+								"dataSourceTitleShort": "Stand-in",
+								"recordId":             "c1",
+								"currentName":          "Crania Retzius, 1781",
+							}
+						}
+					}
+					json.NewEncoder(w).Encode(map[string]interface{}{"names": res})
+				}))
+			defer srv.Close()
+			hti, err := NewHTindex(append(initOpts(), OptJobs(1),
+				OptVerifierURL(srv.URL))...)
+			Expect(err).To(BeNil())
+			Expect(hti.Run()).To(Succeed())
+			data := getTestData(hti.OutputPath)
+			Expect(requests).To(BeNumerically(">", 0))
+			Expect(requests).To(BeNumerically("<=", len(readTitleIDs(hti.OutputPath, "titles.csv"))))
+			var crania int
+			for _, v := range data {
+				if v.NameString == "Crania" {
+					crania++
+					Expect(v.MatchType).To(Equal("Exact"))
+					Expect(v.MatchID).To(Equal("c1"))
+					Expect(v.AcceptedName).To(Equal("Crania Retzius, 1781"))
+					Expect(v.DataSource).To(Equal("Stand-in"))
+				} else {
+					Expect(v.MatchType).To(Equal("NoMatch"))
+				}
+			}
+			Expect(crania).To(BeNumerically(">", 0))
+
+			_, err = NewHTindex(append(initOpts(), OptVerifierURL(srv.URL),
+				OptChecklist("checklist.tsv"))...)
+			Expect(err).ToNot(BeNil())
+			os.Stdout = stdout
+		})
+
+		It("saves OCR quality metrics of pages", func() {
+			stdout := os.Stdout
+			os.Stdout, _ = os.Open(os.DevNull)
+			hti, _ := NewHTindex(initOpts()...)
+			Expect(hti.Run()).To(Succeed())
+			all := len(getTestData(hti.OutputPath))
+			pages := readPages(hti.OutputPath)
+			Expect(len(pages)).To(BeNumerically(">", 0))
+			titleNames := make(map[string]int)
+			for _, p := range pages {
+				num, err := strconv.Atoi(p["NamesNumber"])
+				Expect(err).To(BeNil())
+				if num > 0 {
+					titleNames[p["ID"]] += num
+				}
+				Expect(p["ByteSize"]).ToNot(BeEmpty())
+				if !strings.HasPrefix(p["PageID"], "00") {
+					Expect(p["NonStandardName"]).To(Equal("true"))
+					continue
+				}
+				Expect(p["NonStandardName"]).To(Equal("false"))
+				seq, err := strconv.Atoi(p["PageID"])
+				Expect(err).To(BeNil())
+				Expect(p["Sequence"]).To(Equal(strconv.Itoa(seq)))
+			}
+			Expect(titleNames).To(Equal(resultsCount(getTestData(hti.OutputPath))))
+			var lowQuality int
+			for _, p := range pages {
+				Expect(p["Skipped"]).To(Equal("false"))
+				for _, f := range []string{"AlphaRatio", "DictWordsRatio"} {
+					v, err := strconv.ParseFloat(p[f], 64)
+					Expect(err).To(BeNil())
+					Expect(v).To(BeNumerically(">=", 0))
+					Expect(v).To(BeNumerically("<=", 1))
+				}
+				if v, _ := strconv.ParseFloat(p["DictWordsRatio"], 64); v < 0.8 {
+					lowQuality++
+				}
+			}
+			Expect(lowQuality).To(BeNumerically(">", 0))
+
+			hti, _ = NewHTindex(append(initOpts(), OptMinPageQuality(0.8))...)
+			Expect(hti.Run()).To(Succeed())
+			skipped := make(map[string]struct{})
+			for _, p := range readPages(hti.OutputPath) {
+				if p["Skipped"] == "true" {
+					skipped[p["ID"]+"/"+p["PageID"]] = struct{}{}
+				}
+			}
+			Expect(len(skipped)).To(Equal(lowQuality))
+			data := getTestData(hti.OutputPath)
+			Expect(len(data)).To(BeNumerically("<", all))
+			for _, v := range data {
+				Expect(skipped).ToNot(HaveKey(v.ID + "/" + v.PageID))
+			}
+			os.Stdout = stdout
+		})
+
+		It("finds names split by page breaks in stream mode", func() {
+			stdout := os.Stdout
+			os.Stdout, _ = os.Open(os.DevNull)
+			input, err := filepath.Abs("./testdata/input_paths_tst.txt")
+			Expect(err).To(BeNil())
+			hti, _ := NewHTindex(append(initOpts(), OptInput(input))...)
+			Expect(hti.Run()).To(Succeed())
+			names := make(map[string]struct{})
+			for _, v := range getTestData(hti.OutputPath) {
+				Expect(v.EndPageID).To(BeEmpty())
+				if v.ID == "tst.39000000000001" {
+					names[v.NameString] = struct{}{}
+				}
+			}
+			Expect(names).ToNot(HaveKey("Quercus alba"))
+
+			hti, _ = NewHTindex(append(initOpts(), OptInput(input), OptStream(true))...)
+			Expect(hti.Run()).To(Succeed())
+			texts := readPageTexts(filepath.Join(hti.RootPrefix, "tst", "pairtree_root",
+				"39", "00", "00", "00", "00", "00", "01", "39000000000001",
+				"39000000000001.zip"))
+			type occ struct{ page, name, endPage string }
+			var occs []occ
+			for _, v := range getTestData(hti.OutputPath) {
+				if v.ID != "tst.39000000000001" {
+					continue
+				}
+				occs = append(occs, occ{v.PageID, v.NameString, v.EndPageID})
+				start, _ := strconv.Atoi(v.OffsetStart)
+				end, _ := strconv.Atoi(v.OffsetEnd)
+				words := strings.Fields(v.NameString)
+				// the end of the epithet is not split by hyphenation.
+				tail := words[1][len(words[1])-3:]
+				text := texts[v.PageID]
+				Expect(string(text[start:])).To(HavePrefix(words[0]))
+				if v.EndPageID == "" {
+					Expect(string(text[start:end])).To(ContainSubstring(tail))
+					continue
+				}
+				Expect(string(texts[v.EndPageID][:end])).To(ContainSubstring(tail))
+			}
+			Expect(occs).To(Equal([]occ{
+				{"00000001", "Quercus alba", "00000002"},
+				{"00000002", "Pomatomus saltatrix", ""},
+				{"00000002", "Quercus rubra", ""},
+				{"00000003", "Pomatomus saltatrix", "00000004"},
+			}))
+			os.Stdout = stdout
+		})
+
+		It("does not join pages with different languages in stream mode", func() {
+			stdout := os.Stdout
+			os.Stdout, _ = os.Open(os.DevNull)
+			input, err := filepath.Abs("./testdata/input_paths_tst.txt")
+			Expect(err).To(BeNil())
+			opts := append(initOpts(), OptInput(input), OptStream(true))
+			streamNames := func(hti *HTindex) map[string]string {
+				Expect(hti.Run()).To(Succeed())
+				res := make(map[string]string)
+				for _, v := range getTestData(hti.OutputPath) {
+					if v.ID == "tst.39000000000004" && v.EndPageID != "" {
+						res[v.NameString] = v.PageID + "-" + v.EndPageID
+					}
+				}
+				return res
+			}
+			hti, _ := NewHTindex(opts...)
+			Expect(streamNames(hti)).To(Equal(map[string]string{
+				"Quercus alba":        "00000001-00000002",
+				"Pomatomus saltatrix": "00000003-00000004",
+			}))
+
+			hti, _ = NewHTindex(append(opts, OptLangDetection(LangPage))...)
+			Expect(streamNames(hti)).To(Equal(map[string]string{
+				"Pomatomus saltatrix": "00000003-00000004",
+			}))
+			langs := make(map[string]string)
+			for _, p := range readPages(hti.OutputPath) {
+				if p["ID"] == "tst.39000000000004" {
+					langs[p["PageID"]] = p["Language"]
+				}
+			}
+			Expect(langs).To(Equal(map[string]string{
+				"00000001": "eng", "00000002": "deu", "00000003": "deu",
+				"00000004": "deu",
+			}))
+			os.Stdout = stdout
+		})
+
+		It("cleans OCR texts before name-finding", func() {
+			stdout := os.Stdout
+			os.Stdout, _ = os.Open(os.DevNull)
+			n, err := NewNormalization([]string{"hyphens", "unicode"})
+			Expect(err).To(BeNil())
+			Expect(n.String()).To(Equal("unicode,hyphens"))
+			_, err = NewNormalization([]string{"spaces"})
+			Expect(err).ToNot(BeNil())
+
+			input, err := filepath.Abs("./testdata/input_paths_tst.txt")
+			Expect(err).To(BeNil())
+			texts := readPageTexts(filepath.Join("./testdata", "tst", "pairtree_root",
+				"39", "00", "00", "00", "00", "00", "02", "39000000000002",
+				"39000000000002.zip"))
+			hti, _ := NewHTindex(append(initOpts(), OptInput(input))...)
+			Expect(hti.Run()).To(Succeed())
+			var headerNames int
+			names := make(map[string]struct{})
+			for _, v := range getTestData(hti.OutputPath) {
+				if v.ID != "tst.39000000000002" {
+					continue
+				}
+				names[v.NameString] = struct{}{}
+				if v.Verbatim == "QUERCUS" {
+					headerNames++
+				}
+			}
+			Expect(headerNames).To(Equal(6))
+			Expect(names).ToNot(HaveKey("Carex flacca"))
+
+			hti, _ = NewHTindex(append(initOpts(), OptInput(input),
+				OptNormalization(NormAll))...)
+			Expect(hti.Run()).To(Succeed())
+			type occ struct{ page, name string }
+			var occs []occ
+			for _, v := range getTestData(hti.OutputPath) {
+				if v.ID != "tst.39000000000002" {
+					continue
+				}
+				occs = append(occs, occ{v.PageID, v.NameString})
+				start, _ := strconv.Atoi(v.OffsetStart)
+				end, _ := strconv.Atoi(v.OffsetEnd)
+				words := strings.Fields(v.NameString)
+				text := string(texts[v.PageID][start:end])
+				Expect(text).To(HavePrefix(words[0]))
+				Expect(text).To(HaveSuffix(words[1][len(words[1])-3:]))
+			}
+			Expect(occs).To(Equal([]occ{
+				{"00000001", "Carex flacca"},
+				{"00000001", "Quercus robur"},
+				{"00000002", "Pomatomus saltatrix"},
+				{"00000003", "Quercus alba"},
+				{"00000003", "Pomatomus saltatrix"},
+				{"00000005", "Andricus quercuscalifornicus"},
+			}))
+			os.Stdout = stdout
+		})
+
+		It("flags names in running headers", func() {
+			stdout := os.Stdout
+			os.Stdout, _ = os.Open(os.DevNull)
+			input, err := filepath.Abs("./testdata/input_paths_tst.txt")
+			Expect(err).To(BeNil())
+			hti, _ := NewHTindex(append(initOpts(), OptInput(input))...)
+			Expect(hti.Run()).To(Succeed())
+			for _, v := range getTestData(hti.OutputPath) {
+				Expect(v.InHeader).To(Equal("false"))
+			}
+
+			hti, _ = NewHTindex(append(initOpts(), OptInput(input),
+				OptFlagHeaderNames(true))...)
+			Expect(hti.Run()).To(Succeed())
+			all := getTestData(hti.OutputPath)
+			pages := make(map[string]struct{})
+			// a long title has a name line at the top of 3 out of 40 pages,
+			// it is not a running header.
+			var longPages []string
+			for _, v := range all {
+				if v.ID == "tst.39000000000005" {
+					Expect(v.NameString).To(Equal("Quercus alba"))
+					longPages = append(longPages, v.PageID)
+				}
+				if v.InHeader == "false" {
+					continue
+				}
+				Expect(v.InHeader).To(Equal("true"))
+				Expect(v.ID).To(Equal("tst.39000000000002"))
+				Expect(v.Verbatim).To(Equal("QUERCUS"))
+				pages[v.PageID] = struct{}{}
+			}
+			Expect(len(pages)).To(Equal(6))
+			Expect(longPages).To(Equal([]string{"00000010", "00000020", "00000030"}))
+
+			hti, _ = NewHTindex(append(initOpts(), OptInput(input),
+				OptDropHeaderNames(true))...)
+			Expect(hti.Run()).To(Succeed())
+			data := getTestData(hti.OutputPath)
+			Expect(len(data)).To(Equal(len(all) - len(pages)))
+			for _, v := range data {
+				Expect(v.InHeader).To(Equal("false"))
+			}
+			os.Stdout = stdout
+		})
+
+		It("expands abbreviated genera", func() {
+			stdout := os.Stdout
+			os.Stdout, _ = os.Open(os.DevNull)
+			input, err := filepath.Abs("./testdata/input_paths_tst.txt")
+			Expect(err).To(BeNil())
+			hti, _ := NewHTindex(append(initOpts(), OptInput(input))...)
+			Expect(hti.Run()).To(Succeed())
+			res := make(map[string]string)
+			for _, v := range getTestData(hti.OutputPath) {
+				if v.ID != "tst.39000000000003" {
+					continue
+				}
+				res[v.PageID+" "+v.NameString] = v.ExpandedName
+			}
+			Expect(res).To(Equal(map[string]string{
+				"00000001 Quercus alba":        "|",
+				"00000001 Quercus rubra":       "|",
+				"00000001 Carex flacca":        "|",
+				"00000001 Centaurea jacea":     "|",
+				"00000002 Q. rubra":            "Quercus rubra|high",
+				"00000002 C. flacca":           "Carex flacca|high",
+				"00000002 C. nigra":            "Centaurea nigra|low",
+				"00000002 Q. macrocarpa":       "Quercus macrocarpa|medium",
+				"00000003 Pomatomus saltatrix": "|",
+				"00000003 P. saltatrix":        "Pomatomus saltatrix|high",
+				"00000003 Z. mays":             "|",
+			}))
+			os.Stdout = stdout
+		})
+
+		It("reads METS files of titles", func() {
+			stdout := os.Stdout
+			os.Stdout, _ = os.Open(os.DevNull)
+			input, err := filepath.Abs("./testdata/input_paths_tst.txt")
+			Expect(err).To(BeNil())
+			hti, _ := NewHTindex(append(initOpts(), OptInput(input), OptMETS(true))...)
+			Expect(hti.Run()).To(Succeed())
+			labels := make(map[string]string)
+			for _, p := range readPages(hti.OutputPath) {
+				if p["ID"] == "tst.39000000000001" {
+					labels[p["PageID"]] = p["Label"]
+					Expect(p["Sequence"]).To(Equal(strings.TrimLeft(p["PageID"], "0")))
+				}
+			}
+			Expect(labels).To(Equal(map[string]string{
+				"00000001": "", "00000002": "1", "00000003": "2", "00000004": "3",
+			}))
+			var namesNum int
+			for _, v := range getTestData(hti.OutputPath) {
+				if v.ID == "tst.39000000000001" {
+					Expect(v.PageLabel).To(Equal(labels[v.PageID]))
+					namesNum++
+				}
+			}
+			Expect(namesNum).To(BeNumerically(">", 0))
+
+			errs, err := readErrors(hti.OutputPath)
+			Expect(err).To(BeNil())
+			Expect(errs).ToNot(HaveKey("tst.39000000000001"))
+			Expect(errs).To(HaveKey("tst.39000000000002"))
+			Expect(errs["tst.39000000000002"].pageID).To(Equal("00000003"))
+			Expect(errs["tst.39000000000002"].msg).To(HavePrefix("checksum mismatch"))
+			Expect(errs).To(HaveKey("tst.39000000000003"))
+			Expect(errs["tst.39000000000003"].msg).To(HavePrefix("cannot use METS file"))
+			os.Stdout = stdout
+		})
+
+		It("uses HathiTrust IDs for titles", func() {
+			stdout := os.Stdout
+			os.Stdout, _ = os.Open(os.DevNull)
+			hti, _ := NewHTindex(initOpts()...)
+			Expect(hti.Run()).To(Succeed())
+			ids := readTitleIDs(hti.OutputPath, "titles.csv")
+			Expect(ids).To(ContainElement("uc2.ark:/13960/t6154rj46"))
+			Expect(ids).To(ContainElement("miun.acl9167.0001.001"))
+			Expect(ids).To(ContainElement("mdp.39015027528713"))
+			os.Stdout = stdout
+		})
+
 		// Issue #12
 		It("reports about volumes that have no standard pages", func() {
 			stdout := os.Stdout
@@ -76,6 +736,294 @@ var _ = Describe("Htindex", func() {
 			os.Stdout = stdout
 		})
 
+		It("resumes an interrupted run", func() {
+			stdout := os.Stdout
+			os.Stdout, _ = os.Open(os.DevNull)
+			opts := append(initOpts(), OptOutput(testOutputResume))
+			hti, _ := NewHTindex(opts...)
+			Expect(hti.Run()).To(Succeed())
+			titles := readTitleIDs(hti.OutputPath, "titles.csv")
+			summary := readTitleIDs(hti.OutputPath, "summary.csv")
+
+			cpPath := filepath.Join(hti.OutputPath, "checkpoint.csv")
+			cp, err := ioutil.ReadFile(cpPath)
+			Expect(err).To(BeNil())
+			lines := strings.SplitAfter(string(cp), "\n")
+			Expect(len(lines)).To(BeNumerically(">", 3))
+			// simulates interruption with a partially written title, its record
+			// has no sizes of some files and no new line.
+			cp = []byte(strings.Join(lines[0:3], "") + "uc2.broken,100,200")
+			Expect(ioutil.WriteFile(cpPath, cp, 0644)).To(Succeed())
+			res, err := os.OpenFile(filepath.Join(hti.OutputPath, "results.csv"),
+				os.O_APPEND|os.O_WRONLY, 0644)
+			Expect(err).To(BeNil())
+			_, err = res.WriteString("partial,row")
+			Expect(err).To(BeNil())
+			res.Close()
+
+			hti, _ = NewHTindex(append(opts, OptResume(true))...)
+			Expect(hti.Run()).To(Succeed())
+			Expect(readTitleIDs(hti.OutputPath, "titles.csv")).To(ConsistOf(titles))
+			Expect(readTitleIDs(hti.OutputPath, "summary.csv")).To(ConsistOf(summary))
+			pages := make(map[string]int)
+			for _, p := range readPages(hti.OutputPath) {
+				pages[p["ID"]+"/"+p["PageID"]]++
+			}
+			for _, v := range pages {
+				Expect(v).To(Equal(1))
+			}
+			hasRepetitions, err := hasRepetitions(hti)
+			Expect(err).To(BeNil())
+			Expect(hasRepetitions).To(BeFalse())
+			os.Stdout = stdout
+		})
+
+		It("reprocesses only new and changed titles", func() {
+			stdout := os.Stdout
+			os.Stdout, _ = os.Open(os.DevNull)
+			hti, _ := NewHTindex(initOpts()...)
+			Expect(hti.Run()).To(Succeed())
+			prevCount := resultsCount(getTestData(hti.OutputPath))
+
+			prevPath := testOutput + "-prev"
+			Expect(os.RemoveAll(prevPath)).To(Succeed())
+			Expect(os.Rename(hti.OutputPath, prevPath)).To(Succeed())
+			// changes SHA256 of a title and removes another one from the input
+			changed, removed := "uc2.ark:/13960/t6154rj46", "coo1.ark:/13960/t9t15jw5n"
+			titles, err := ioutil.ReadFile(filepath.Join(prevPath, "titles.csv"))
+			Expect(err).To(BeNil())
+			lines := strings.Split(string(titles), "\n")
+			for i, l := range lines {
+				if strings.HasPrefix(l, changed+",") {
+					lines[i] = strings.Replace(l, ",", ",changed", 1)
+				}
+			}
+			err = ioutil.WriteFile(filepath.Join(prevPath, "titles.csv"),
+				[]byte(strings.Join(lines, "\n")), 0644)
+			Expect(err).To(BeNil())
+			input, err := ioutil.ReadFile("./testdata/input_paths_small.txt")
+			Expect(err).To(BeNil())
+			lines = strings.Split(string(input), "\n")
+			var newInput []string
+			for _, l := range lines {
+				if !strings.Contains(l, "t9t15jw5n") {
+					newInput = append(newInput, l)
+				}
+			}
+			inputPath := filepath.Join(os.TempDir(), "htindex-test-input.txt")
+			err = ioutil.WriteFile(inputPath,
+				[]byte(strings.Join(newInput, "\n")), 0644)
+			Expect(err).To(BeNil())
+
+			opts := append(initOpts(), OptInput(inputPath), OptPrevious(prevPath))
+			hti, _ = NewHTindex(opts...)
+			Expect(hti.Run()).To(Succeed())
+			count := resultsCount(getTestData(hti.OutputPath))
+			Expect(count[changed]).To(Equal(prevCount[changed]))
+			Expect(count[removed]).To(Equal(0))
+			delete(prevCount, removed)
+			Expect(count).To(Equal(prevCount))
+			// rows of unchanged titles are copied with their old time stamps,
+			// names are found again only in the changed title
+			prevStamps := resultsTimeStamps(getTestData(prevPath))
+			stamps := resultsTimeStamps(getTestData(hti.OutputPath))
+			for id := range count {
+				if id == changed {
+					Expect(stamps[id]).ToNot(Equal(prevStamps[id]))
+				} else {
+					Expect(stamps[id]).To(Equal(prevStamps[id]))
+				}
+			}
+			removedIDs := readTitleIDs(hti.OutputPath, "removed.csv")
+			Expect(removedIDs).To(Equal([]string{removed}))
+			Expect(readTitleIDs(hti.OutputPath, "titles.csv")).To(ContainElement(changed))
+			Expect(summaryCount(hti.OutputPath)).To(Equal(count))
+			stats := readStats(hti.OutputPath)
+			var occNum int
+			for _, v := range count {
+				occNum += v
+			}
+			Expect(stats.OccurrencesNumber).To(Equal(occNum))
+			os.Stdout = stdout
+		})
+
+		It("finds titles by walking pairtree directories", func() {
+			stdout := os.Stdout
+			os.Stdout, _ = os.Open(os.DevNull)
+			opts := append(initOpts(), OptInput(""), OptWalk(true),
+				OptNamespaces([]string{"yale", "dul1", "gri", "ucw", "nc01"}),
+				OptExcludeNamespaces([]string{"gri"}),
+			)
+			hti, _ := NewHTindex(opts...)
+			Expect(hti.Run()).To(Succeed())
+			ids := readTitleIDs(hti.OutputPath, "titles.csv")
+			Expect(len(ids)).To(Equal(5))
+			for _, id := range ids {
+				ns := strings.Split(id, ".")[0]
+				Expect(ns).To(BeElementOf("yale", "dul1", "ucw", "nc01"))
+			}
+			errs, err := readErrors(hti.OutputPath)
+			Expect(err).To(BeNil())
+			Expect(errs).To(HaveKey("yale.empty"))
+			os.Stdout = stdout
+		})
+
+		It("saves output in Parquet format", func() {
+			stdout := os.Stdout
+			os.Stdout, _ = os.Open(os.DevNull)
+			hti, _ := NewHTindex(initOpts()...)
+			Expect(hti.Run()).To(Succeed())
+			csvNum := len(getTestData(hti.OutputPath))
+			titlesNum := len(readTitleIDs(hti.OutputPath, "titles.csv"))
+
+			opts := append(initOpts(), OptOutput(testOutputParquet),
+				OptFormat(Parquet), OptRowGroupSize(1))
+			hti, _ = NewHTindex(opts...)
+			Expect(hti.Run()).To(Succeed())
+			res := readParquet(hti.OutputPath, "results.parquet", new(parquetResult))
+			Expect(len(res)).To(Equal(csvNum))
+			var hasOdds bool
+			for _, v := range res {
+				r := v.(parquetResult)
+				Expect(r.OffsetEnd).To(BeNumerically(">", r.OffsetStart))
+				if r.Odds > 0 && r.Odds != float64(int(r.Odds)) {
+					hasOdds = true
+				}
+			}
+			Expect(hasOdds).To(BeTrue())
+			titles := readParquet(hti.OutputPath, "titles.parquet", new(parquetTitle))
+			Expect(len(titles)).To(Equal(titlesNum))
+			errs := readParquet(hti.OutputPath, "errors.parquet", new(parquetError))
+			Expect(len(errs)).To(BeNumerically(">", 0))
+			os.Stdout = stdout
+		})
+
+		It("saves output in JSON Lines format", func() {
+			stdout := os.Stdout
+			os.Stdout, _ = os.Open(os.DevNull)
+			hti, _ := NewHTindex(initOpts()...)
+			Expect(hti.Run()).To(Succeed())
+			csvNum := len(getTestData(hti.OutputPath))
+			titlesNum := len(readTitleIDs(hti.OutputPath, "titles.csv"))
+
+			opts := append(initOpts(), OptOutput(testOutputJSONL), OptFormat(JSONL))
+			hti, _ = NewHTindex(opts...)
+			Expect(hti.Run()).To(Succeed())
+			var titles []jsonTitle
+			readJSONL(hti.OutputPath, "titles.jsonl", func(l []byte) {
+				var t jsonTitle
+				Expect(json.Unmarshal(l, &t)).To(Succeed())
+				titles = append(titles, t)
+			})
+			Expect(len(titles)).To(Equal(titlesNum))
+			var namesNum int
+			var hasWords bool
+			for _, t := range titles {
+				var titleNames int
+				for _, p := range t.Pages {
+					Expect(p.ID).ToNot(BeEmpty())
+					for _, n := range p.Names {
+						Expect(n.PageID).To(Equal(p.ID))
+						if len(n.WordsBefore) > 0 {
+							hasWords = true
+						}
+					}
+					titleNames += len(p.Names)
+				}
+				Expect(titleNames).To(Equal(t.NamesOccurrences))
+				namesNum += titleNames
+			}
+			Expect(namesNum).To(Equal(csvNum))
+			Expect(hasWords).To(BeTrue())
+			var errsNum int
+			readJSONL(hti.OutputPath, "errors.jsonl", func(l []byte) {
+				var e Error
+				Expect(json.Unmarshal(l, &e)).To(Succeed())
+				Expect(e.Message).ToNot(BeEmpty())
+				errsNum++
+			})
+			Expect(errsNum).To(BeNumerically(">", 0))
+			os.Stdout = stdout
+		})
+
+		It("saves output to SQLite database", func() {
+			stdout := os.Stdout
+			os.Stdout, _ = os.Open(os.DevNull)
+			hti, _ := NewHTindex(initOpts()...)
+			Expect(hti.Run()).To(Succeed())
+			data := getTestData(hti.OutputPath)
+			titlesNum := len(readTitleIDs(hti.OutputPath, "titles.csv"))
+
+			opts := append(initOpts(), OptOutput(testOutputSQLite), OptFormat(SQLite))
+			hti, _ = NewHTindex(opts...)
+			Expect(hti.Run()).To(Succeed())
+			db, err := sql.Open("sqlite", filepath.Join(hti.OutputPath, "htindex.sqlite"))
+			Expect(err).To(BeNil())
+			defer db.Close()
+			var num int
+			Expect(db.QueryRow("SELECT count(*) FROM titles").Scan(&num)).To(Succeed())
+			Expect(num).To(Equal(titlesNum))
+			Expect(db.QueryRow("SELECT count(*) FROM occurrences").Scan(&num)).To(Succeed())
+			Expect(num).To(Equal(len(data)))
+			Expect(db.QueryRow("SELECT count(*) FROM errors").Scan(&num)).To(Succeed())
+			Expect(num).To(BeNumerically(">", 0))
+
+			name := data[0].NameString
+			titles := make(map[string]struct{})
+			for _, v := range data {
+				if v.NameString == name {
+					titles[v.ID] = struct{}{}
+				}
+			}
+			rows, err := db.Query(`SELECT DISTINCT p.title_id FROM occurrences o
+				JOIN name_strings n ON n.id = o.name_string_id
+				JOIN pages p ON p.id = o.page_id
+				WHERE n.name = ?`, name)
+			Expect(err).To(BeNil())
+			defer rows.Close()
+			var ids []string
+			for rows.Next() {
+				var id string
+				Expect(rows.Scan(&id)).To(Succeed())
+				Expect(titles).To(HaveKey(id))
+				ids = append(ids, id)
+			}
+			Expect(len(ids)).To(Equal(len(titles)))
+
+			input, err := filepath.Abs("./testdata/input_paths_dup.txt")
+			Expect(err).To(BeNil())
+			hti, _ = NewHTindex(append(opts, OptInput(input))...)
+			Expect(hti.Run()).To(Succeed())
+			dup, err := sql.Open("sqlite", filepath.Join(hti.OutputPath, "htindex.sqlite"))
+			Expect(err).To(BeNil())
+			defer dup.Close()
+			Expect(dup.QueryRow("SELECT count(*) FROM titles").Scan(&num)).To(Succeed())
+			Expect(num).To(Equal(2))
+			Expect(dup.QueryRow(`SELECT count(*) FROM pages
+				WHERE title_id = 'tst.39000000000001'`).Scan(&num)).To(Succeed())
+			Expect(num).To(Equal(4))
+			os.Stdout = stdout
+		})
+
+		It("sends output to a custom sink", func() {
+			stdout := os.Stdout
+			os.Stdout, _ = os.Open(os.DevNull)
+			hti, _ := NewHTindex(initOpts()...)
+			Expect(hti.Run()).To(Succeed())
+			resCount := resultsCount(getTestData(hti.OutputPath))
+
+			sink := &memorySink{occs: make(map[string]int)}
+			hti, _ = NewHTindex(append(initOpts(), OptSink(sink))...)
+			Expect(hti.Run()).To(Succeed())
+			Expect(sink.closed).To(BeTrue())
+			Expect(len(sink.titles)).To(Equal(len(resCount) + 1))
+			for id, num := range resCount {
+				Expect(sink.occs[id]).To(Equal(num))
+			}
+			Expect(sink.errs).To(ContainElement("no pages detected"))
+			os.Stdout = stdout
+		})
+
 		Measure("Going through titles fast enough", func(b Benchmarker) {
 			stdout := os.Stdout
 			os.Stdout, _ = os.Open(os.DevNull)
@@ -99,24 +1047,57 @@ const (
 	wordsBeforeF
 	nameStringF
 	wordsAfterF
+	annotNomenF
 	offsetStartF
 	offsetEndF
 	oddsF
 	kindF
+	canonicalF
+	canonicalFullF
+	cardinalityF
+	qualityF
+	nameIDF
+	languageF
+	confidenceClassF
+	matchIDF
+	acceptedNameF
+	matchTypeF
+	dataSourceF
+	endPageIDF
+	inHeaderF
+	expandedNameF
+	expansionConfidenceF
+	pageLabelF
 )
 
 type testData struct {
-	TimeStamp   string
-	ID          string
-	PageID      string
-	Verbatim    string
-	WordsBefore string
-	NameString  string
-	WordsAfter  string
-	OffsetStart string
-	OffsetEnd   string
-	Odds        string
-	Kind        string
+	TimeStamp       string
+	ID              string
+	PageID          string
+	Verbatim        string
+	WordsBefore     string
+	NameString      string
+	WordsAfter      string
+	OffsetStart     string
+	OffsetEnd       string
+	Odds            string
+	Kind            string
+	Canonical       string
+	CanonicalFull   string
+	Cardinality     string
+	Quality         string
+	NameID          string
+	Language        string
+	ConfidenceClass string
+	MatchID         string
+	AcceptedName    string
+	MatchType       string
+	DataSource      string
+	EndPageID       string
+	InHeader        string
+	// ExpandedName and its confidence are joined by '|'.
+	ExpandedName string
+	PageLabel    string
 }
 
 type htiError struct {
@@ -144,17 +1125,32 @@ func getTestData(path string) []testData {
 		}
 		Expect(err).To(BeNil())
 		datum := testData{
-			TimeStamp:   v[timeStampF],
-			ID:          v[idF],
-			PageID:      v[pageIDF],
-			Verbatim:    v[verbatimF],
-			NameString:  v[nameStringF],
-			OffsetStart: v[offsetStartF],
-			OffsetEnd:   v[offsetEndF],
-			WordsBefore: v[wordsBeforeF],
-			WordsAfter:  v[wordsAfterF],
-			Odds:        v[oddsF],
-			Kind:        v[kindF],
+			TimeStamp:       v[timeStampF],
+			ID:              v[idF],
+			PageID:          v[pageIDF],
+			Verbatim:        v[verbatimF],
+			NameString:      v[nameStringF],
+			OffsetStart:     v[offsetStartF],
+			OffsetEnd:       v[offsetEndF],
+			WordsBefore:     v[wordsBeforeF],
+			WordsAfter:      v[wordsAfterF],
+			Odds:            v[oddsF],
+			Kind:            v[kindF],
+			Canonical:       v[canonicalF],
+			CanonicalFull:   v[canonicalFullF],
+			Cardinality:     v[cardinalityF],
+			Quality:         v[qualityF],
+			NameID:          v[nameIDF],
+			Language:        v[languageF],
+			ConfidenceClass: v[confidenceClassF],
+			MatchID:         v[matchIDF],
+			AcceptedName:    v[acceptedNameF],
+			MatchType:       v[matchTypeF],
+			DataSource:      v[dataSourceF],
+			EndPageID:       v[endPageIDF],
+			InHeader:        v[inHeaderF],
+			ExpandedName:    v[expandedNameF] + "|" + v[expansionConfidenceF],
+			PageLabel:       v[pageLabelF],
 		}
 		res = append(res, datum)
 	}
@@ -190,6 +1186,196 @@ func readErrors(path string) (map[string]*htiError, error) {
 	return res, nil
 }
 
+type memorySink struct {
+	titles []*Title
+	occs   map[string]int
+	errs   []string
+	closed bool
+}
+
+func (s *memorySink) WriteTitle(t *Title) error {
+	s.titles = append(s.titles, t)
+	return nil
+}
+
+func (s *memorySink) WriteOccurrences(titleID string, occs []Occurrence) error {
+	s.occs[titleID] += len(occs)
+	return nil
+}
+
+func (s *memorySink) WriteError(e *Error) error {
+	s.errs = append(s.errs, e.Message)
+	return nil
+}
+
+func (s *memorySink) Close() error {
+	s.closed = true
+	return nil
+}
+
+type parquetResult struct {
+	ID          string   `parquet:"name=ID, type=UTF8"`
+	WordsBefore []string `parquet:"name=WordsBefore, type=LIST, valuetype=UTF8"`
+	OffsetStart int32    `parquet:"name=OffsetStart, type=INT32"`
+	OffsetEnd   int32    `parquet:"name=OffsetEnd, type=INT32"`
+	Odds        float64  `parquet:"name=Odds, type=DOUBLE"`
+}
+
+type parquetTitle struct {
+	ID          string `parquet:"name=ID, type=UTF8"`
+	PagesNumber int32  `parquet:"name=PagesNumber, type=INT32"`
+}
+
+type parquetError struct {
+	TitleID string `parquet:"name=TitleID, type=UTF8"`
+	Error   string `parquet:"name=Error, type=UTF8"`
+}
+
+func readParquet(path, file string, obj interface{}) []interface{} {
+	fr, err := local.NewLocalFileReader(filepath.Join(path, file))
+	Expect(err).To(BeNil())
+	defer fr.Close()
+	pr, err := reader.NewParquetReader(fr, obj, 1)
+	Expect(err).To(BeNil())
+	defer pr.ReadStop()
+	num := int(pr.GetNumRows())
+	rows := reflect.New(reflect.SliceOf(reflect.TypeOf(obj).Elem()))
+	rows.Elem().Set(reflect.MakeSlice(rows.Elem().Type(), num, num))
+	Expect(pr.Read(rows.Interface())).To(Succeed())
+	res := make([]interface{}, num)
+	for i := range res {
+		res[i] = rows.Elem().Index(i).Interface()
+	}
+	return res
+}
+
+type jsonTitle struct {
+	Title
+	Pages []struct {
+		ID    string       `json:"id"`
+		Names []Occurrence `json:"names"`
+	} `json:"pages"`
+}
+
+func readJSONL(path, file string, fn func([]byte)) {
+	f, err := os.Open(filepath.Join(path, file))
+	Expect(err).To(BeNil())
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for sc.Scan() {
+		fn(sc.Bytes())
+	}
+	Expect(sc.Err()).To(BeNil())
+}
+
+func readStats(path string) *Stats {
+	data, err := ioutil.ReadFile(filepath.Join(path, "stats.json"))
+	Expect(err).To(BeNil())
+	res := &Stats{}
+	Expect(json.Unmarshal(data, res)).To(Succeed())
+	return res
+}
+
+func summaryCount(path string) map[string]int {
+	res := make(map[string]int)
+	f, err := os.Open(filepath.Join(path, "summary.csv"))
+	Expect(err).To(BeNil())
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	Expect(err).To(BeNil())
+	for _, row := range rows[1:] {
+		num, err := strconv.Atoi(row[2])
+		Expect(err).To(BeNil())
+		res[row[0]] += num
+	}
+	return res
+}
+
+func resultsCount(data []testData) map[string]int {
+	res := make(map[string]int)
+	for _, v := range data {
+		res[v.ID]++
+	}
+	return res
+}
+
+// resultsTimeStamps returns time stamps of results for every title.
+func resultsTimeStamps(data []testData) map[string][]string {
+	res := make(map[string][]string)
+	for _, v := range data {
+		res[v.ID] = append(res[v.ID], v.TimeStamp)
+	}
+	for _, v := range res {
+		sort.Strings(v)
+	}
+	return res
+}
+
+// readPages returns rows of pages.csv.
+func readPages(path string) []map[string]string {
+	f, err := os.Open(filepath.Join(path, "pages.csv"))
+	Expect(err).To(BeNil())
+	defer f.Close()
+	ls, err := csv.NewReader(f).ReadAll()
+	Expect(err).To(BeNil())
+	res := make([]map[string]string, 0, len(ls))
+	for _, v := range ls[1:] {
+		row := make(map[string]string)
+		for i, k := range ls[0] {
+			row[k] = v[i]
+		}
+		res = append(res, row)
+	}
+	return res
+}
+
+// readPageTexts returns texts of pages of a zipped title.
+func readPageTexts(path string) map[string][]rune {
+	r, err := zip.OpenReader(path)
+	Expect(err).To(BeNil())
+	defer r.Close()
+	res := make(map[string][]rune)
+	for _, f := range r.File {
+		rc, err := f.Open()
+		Expect(err).To(BeNil())
+		text, err := ioutil.ReadAll(rc)
+		Expect(err).To(BeNil())
+		rc.Close()
+		id := strings.TrimSuffix(filepath.Base(f.Name), ".txt")
+		res[id] = []rune(string(text))
+	}
+	return res
+}
+
+// readTitleLanguages returns languages of titles from titles.csv.
+func readTitleLanguages(path string) map[string]string {
+	f, err := os.Open(filepath.Join(path, "titles.csv"))
+	Expect(err).To(BeNil())
+	defer f.Close()
+	ls, err := csv.NewReader(f).ReadAll()
+	Expect(err).To(BeNil())
+	Expect(ls[0][len(ls[0])-1]).To(Equal("Language"))
+	res := make(map[string]string)
+	for _, v := range ls[1:] {
+		res[v[0]] = v[len(v)-1]
+	}
+	return res
+}
+
+func readTitleIDs(path, file string) []string {
+	f, err := os.Open(filepath.Join(path, file))
+	Expect(err).To(BeNil())
+	defer f.Close()
+	ls, err := csv.NewReader(f).ReadAll()
+	Expect(err).To(BeNil())
+	res := make([]string, 0, len(ls))
+	for _, v := range ls[1:] {
+		res = append(res, v[0])
+	}
+	return res
+}
+
 func initOpts() []Option {
 	root, err := filepath.Abs("./testdata")
 	Expect(err).ToNot(HaveOccurred())
@@ -0,0 +1,264 @@
+package htindex
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// previous keeps data about titles from the output of a previous run. It
+// allows to avoid name-finding in titles that did not change since then.
+type previous struct {
+	// titles contains records from titles.csv of the previous run, where
+	// keys are titles' IDs.
+	titles map[string]map[string]string
+	// seen keeps IDs of titles that are present in the current input.
+	seen map[string]struct{}
+	// unchanged keeps IDs of titles that have the same SHA256 as during
+	// the previous run.
+	unchanged map[string]struct{}
+}
+
+// loadPrevious reads titles of a previous run. If incremental mode is not
+// set, it returns nil.
+func (hti *HTindex) loadPrevious() (*previous, error) {
+	if hti.PreviousPath == "" {
+		return nil, nil
+	}
+	if hti.Resume {
+		return nil, fmt.Errorf("incremental mode cannot be used with resume")
+	}
+	if hti.Sink != nil || hti.Format != CSV {
+		return nil, fmt.Errorf("incremental mode works only with csv format")
+	}
+	prevPath, err := filepath.Abs(hti.PreviousPath)
+	if err != nil {
+		return nil, err
+	}
+	outPath, err := filepath.Abs(hti.OutputPath)
+	if err != nil {
+		return nil, err
+	}
+	if prevPath == outPath {
+		return nil, fmt.Errorf("previous and output directories must differ")
+	}
+
+	prev := &previous{
+		titles:    make(map[string]map[string]string),
+		seen:      make(map[string]struct{}),
+		unchanged: make(map[string]struct{}),
+	}
+	err = readCSV(filepath.Join(prevPath, "titles.csv"),
+		func(row map[string]string) error {
+			prev.titles[row["ID"]] = row
+			return nil
+		})
+	return prev, err
+}
+
+// isUnchanged checks if a title was processed during the previous run and
+// its zip file did not change since then.
+func (prev *previous) isUnchanged(t *title) bool {
+	if prev == nil || t.sha256 == "n/a" {
+		return false
+	}
+	row, ok := prev.titles[t.id]
+	return ok && row["SHA256"] == t.sha256
+}
+
+// title creates metadata of an unchanged title from the previous run.
+func (prev *previous) title(id string) *Title {
+	row := prev.titles[id]
+	pagesNum, _ := strconv.Atoi(row["PagesNumber"])
+	badPagesNum, _ := strconv.Atoi(row["BadPagesNumber"])
+	namesNum, _ := strconv.Atoi(row["NamesOccurences"])
+	return &Title{
+		ID:               id,
+		SHA256:           row["SHA256"],
+		Path:             row["Path"],
+		PagesNumber:      pagesNum,
+		BadPagesNumber:   badPagesNum,
+		NamesOccurrences: namesNum,
+		Language:         row["Language"],
+		Unchanged:        true,
+	}
+}
+
+// mergePrevious copies results and pages of unchanged titles from the
+// previous run to the current output, creates their summaries, adds them to
+// statistics, and saves titles that disappeared from the input to
+// removed.csv file.
+func (hti *HTindex) mergePrevious(prev *previous, sc *statsCollector) error {
+	if prev == nil {
+		return nil
+	}
+	f, err := os.OpenFile(filepath.Join(hti.OutputPath, "results.csv"),
+		os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	sf, err := os.OpenFile(filepath.Join(hti.OutputPath, "summary.csv"),
+		os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer sf.Close()
+	sw := csv.NewWriter(sf)
+
+	// results of a title follow each other, so a summary is written as
+	// soon as the next title starts.
+	var titleID string
+	var occs []Occurrence
+	writeSummary := func() error {
+		if len(occs) == 0 {
+			return nil
+		}
+		sc.addOccurrences(titleID, occs)
+		for _, v := range summarize(occs) {
+			if err := sw.Write(summaryRow(titleID, v)); err != nil {
+				return err
+			}
+		}
+		occs = occs[:0]
+		return nil
+	}
+	err = readCSV(filepath.Join(hti.PreviousPath, "results.csv"),
+		func(row map[string]string) error {
+			if _, ok := prev.unchanged[row["ID"]]; !ok {
+				return nil
+			}
+			if row["ID"] != titleID {
+				if err := writeSummary(); err != nil {
+					return err
+				}
+				titleID = row["ID"]
+			}
+			odds, _ := strconv.ParseFloat(row["Odds"], 64)
+			// results of older versions do not have confidence classes.
+			if row["ConfidenceClass"] == "" {
+				row["ConfidenceClass"] = confidenceClass(odds)
+			}
+			occs = append(occs, Occurrence{
+				PageID:     row["PageID"],
+				NameString: row["NameString"],
+				Canonical:  row["Canonical"],
+				Kind:       row["Kind"],
+				Odds:       odds,
+			})
+			return w.Write(csvRow(resultsHeader, row))
+		})
+	if err == nil {
+		err = writeSummary()
+	}
+	if err != nil {
+		return err
+	}
+	for _, cw := range []*csv.Writer{w, sw} {
+		cw.Flush()
+		if err = cw.Error(); err != nil {
+			return err
+		}
+	}
+	if err = hti.copyPages(prev); err != nil {
+		return err
+	}
+
+	removed, err := os.Create(filepath.Join(hti.OutputPath, "removed.csv"))
+	if err != nil {
+		return err
+	}
+	defer removed.Close()
+	rw := csv.NewWriter(removed)
+	_ = rw.Write(titlesHeader)
+	var removedNum int
+	for id, row := range prev.titles {
+		if _, ok := prev.seen[id]; ok {
+			continue
+		}
+		removedNum++
+		_ = rw.Write(csvRow(titlesHeader, row))
+	}
+	rw.Flush()
+	fmt.Printf("Titles unchanged: %d, removed: %d\n",
+		len(prev.unchanged), removedNum)
+	return rw.Error()
+}
+
+// copyPages copies pages of unchanged titles from the previous run. The
+// output of older versions does not have pages.csv file.
+func (hti *HTindex) copyPages(prev *previous) error {
+	path := filepath.Join(hti.PreviousPath, "pages.csv")
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	f, err := os.OpenFile(filepath.Join(hti.OutputPath, "pages.csv"),
+		os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	err = readCSV(path, func(row map[string]string) error {
+		if _, ok := prev.unchanged[row["ID"]]; !ok {
+			return nil
+		}
+		return w.Write(csvRow(pagesHeader, row))
+	})
+	if err != nil {
+		return err
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// readCSV reads a CSV file with a header and feeds its rows, converted to
+// maps of field name to value, to a given function.
+func readCSV(path string, fn func(map[string]string) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for {
+		v, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		row := make(map[string]string, len(header))
+		for i, k := range header {
+			if i < len(v) {
+				row[k] = v[i]
+			}
+		}
+		if err = fn(row); err != nil {
+			return err
+		}
+	}
+}
+
+// csvRow arranges values from a row according to a given header. Fields
+// absent in the row become empty strings.
+func csvRow(header []string, row map[string]string) []string {
+	res := make([]string, len(header))
+	for i, k := range header {
+		res[i] = row[k]
+	}
+	return res
+}
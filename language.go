@@ -0,0 +1,150 @@
+package htindex
+
+import (
+	"fmt"
+
+	"github.com/abadojack/whatlanggo"
+	"github.com/gnames/gnfinder/lang"
+)
+
+// LangDetection determines how languages of titles are detected.
+type LangDetection int
+
+// Modes of language detection.
+const (
+	// LangTitle detects one language for all pages of a title.
+	LangTitle LangDetection = iota
+	// LangPage detects language of every page separately. The language of
+	// the title is the most common language of its pages.
+	LangPage
+	// LangNone turns language detection off, all texts are treated as
+	// English.
+	LangNone
+)
+
+func (ld LangDetection) String() string {
+	modes := [...]string{"title", "page", "none"}
+	return modes[ld]
+}
+
+// NewLangDetection takes a string and returns matching LangDetection, or
+// an error if such mode does not exist.
+func NewLangDetection(s string) (LangDetection, error) {
+	for _, ld := range []LangDetection{LangTitle, LangPage, LangNone} {
+		if ld.String() == s {
+			return ld, nil
+		}
+	}
+	return LangTitle, fmt.Errorf("unknown language detection '%s'", s)
+}
+
+// langSampleSize is the maximal number of bytes of a page used for
+// language detection.
+const langSampleSize = 40000
+
+// langSamplePages is the number of pages used for detection of a title's
+// language.
+const langSamplePages = 20
+
+// langUndetermined is the ISO 639-3 code for texts where language cannot be
+// detected reliably, for example because of bad OCR.
+const langUndetermined = "und"
+
+// detectLanguages finds languages of pages according to the language
+// detection mode, or uses the Language setting if it is given. It returns
+// ISO 639-3 codes of languages of the title and of every page, and
+// languages that gnfinder should use for pages.
+func (hti *HTindex) detectLanguages(pages []page) (string, []string,
+	[]lang.Language) {
+	codes := make([]string, len(pages))
+	langs := make([]lang.Language, len(pages))
+	if hti.Language != "" {
+		l := finderLang(hti.Language)
+		for i := range pages {
+			codes[i], langs[i] = hti.Language, l
+		}
+		return hti.Language, codes, langs
+	}
+	switch hti.LangDetection {
+	case LangTitle:
+		var sample []string
+		step := len(pages)/langSamplePages + 1
+		for i := 0; i < len(pages); i += step {
+			_, code := detectLanguage(pages[i].text)
+			sample = append(sample, code)
+		}
+		code := titleLanguage(sample)
+		l := finderLang(code)
+		for i := range pages {
+			codes[i], langs[i] = code, l
+		}
+		return code, codes, langs
+	case LangPage:
+		for i, p := range pages {
+			langs[i], codes[i] = detectLanguage(p.text)
+		}
+		code := titleLanguage(codes)
+		// pages with undetermined language use the language of the title.
+		l := finderLang(code)
+		for i := range pages {
+			if codes[i] == langUndetermined {
+				langs[i] = l
+			}
+		}
+		return code, codes, langs
+	default:
+		for i := range langs {
+			langs[i] = lang.English
+		}
+		return "", codes, langs
+	}
+}
+
+// detectLanguage finds the language of a text. It returns a language for
+// gnfinder and ISO 639-3 code of the detected language. If the detection
+// is not reliable, the code is 'und'.
+func detectLanguage(text []byte) (lang.Language, string) {
+	if len(text) > langSampleSize {
+		text = text[:langSampleSize]
+	}
+	info := whatlanggo.Detect(string(text))
+	if !info.IsReliable() {
+		return lang.English, langUndetermined
+	}
+	code := info.Lang.Iso6393()
+	return finderLang(code), code
+}
+
+// finderLang returns a gnfinder language for ISO 639-3 code. If gnfinder
+// does not support the language, English is used.
+func finderLang(code string) lang.Language {
+	l, err := lang.NewLanguage(code)
+	if err != nil {
+		return lang.English
+	}
+	return l
+}
+
+// titleLanguage returns the most frequent language code of pages. If less
+// than half of the pages have a reliably detected language, the title's
+// language is undetermined. In case of a tie the code that reached the
+// count first wins.
+func titleLanguage(codes []string) string {
+	counts := make(map[string]int)
+	res := langUndetermined
+	var determined int
+	for _, c := range codes {
+		if c == langUndetermined {
+			continue
+		}
+		determined++
+		counts[c]++
+		if counts[c] > counts[res] {
+			res = c
+		}
+	}
+	if determined*2 < len(codes) {
+		return langUndetermined
+	}
+	return res
+}
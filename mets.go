@@ -0,0 +1,41 @@
+package htindex
+
+import "github.com/gnames/htindex/mets"
+
+// checkMETS reads the METS file located next to the zip file of a title. It
+// takes sequence numbers and printed page numbers of pages from the METS
+// file and validates texts of pages against their checksums. Problems are
+// sent to errCh, they do not stop processing of the title.
+func (t *title) checkMETS(zipPath string, pcs []page, errCh chan<- *Error) {
+	m, err := mets.Load(mets.Path(zipPath))
+	if err != nil {
+		errCh <- &Error{TimeStamp: ts(), TitleID: t.id,
+			Message: "cannot use METS file: " + err.Error()}
+		return
+	}
+	ids := make(map[string]struct{}, len(pcs))
+	for i, p := range pcs {
+		ids[p.id] = struct{}{}
+		mp, ok := m.Page(p.id)
+		if !ok {
+			errCh <- &Error{TimeStamp: ts(), TitleID: t.id, PageID: p.id,
+				Message: "page is absent in METS file"}
+			continue
+		}
+		meta := &t.pages[i].meta
+		if mp.Sequence > 0 {
+			meta.Sequence = mp.Sequence
+		}
+		meta.Label = mp.OrderLabel
+		if err = mp.Verify(p.text); err != nil {
+			errCh <- &Error{TimeStamp: ts(), TitleID: t.id, PageID: p.id,
+				Message: err.Error()}
+		}
+	}
+	for _, mp := range m.Pages {
+		if _, ok := ids[mp.ID]; !ok {
+			errCh <- &Error{TimeStamp: ts(), TitleID: t.id, PageID: mp.ID,
+				Message: "page of METS file is absent in zip file"}
+		}
+	}
+}
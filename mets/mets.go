@@ -0,0 +1,216 @@
+// Package mets reads HathiTrust METS files. Every volume package in a
+// pairtree is a zip file with texts of pages and a '.mets.xml' file next to
+// it. The METS file describes files of the volume with their checksums, and
+// the physical structure of the volume with the order of pages and their
+// printed page numbers (order labels).
+//
+// Only texts of pages (the file group with USE="ocr") are read, images and
+// other files are ignored.
+package mets
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Ext is the extension of METS files.
+const Ext = ".mets.xml"
+
+// Page is a text file of a page described in a METS file.
+type Page struct {
+	// ID is the name of the page file without extension, for example
+	// '00000042'.
+	ID string
+	// File is the name of the page file, for example '00000042.txt'.
+	File string
+	// Sequence is the number of the page in the scanned volume.
+	Sequence int
+	// OrderLabel is the page number printed on the page, for example 'xii'
+	// or '27'. It is empty for pages without printed numbers.
+	OrderLabel string
+	// Label contains features of the page, for example 'FRONT_COVER' or
+	// 'IMAGE_ON_PAGE, RIGHT'.
+	Label string
+	// Size is the size of the file in bytes, 0 if it is unknown.
+	Size int64
+	// Checksum is a hex-encoded checksum of the file.
+	Checksum string
+	// ChecksumType is the algorithm of the checksum: MD5, SHA-1 or SHA-256.
+	ChecksumType string
+}
+
+// METS contains pages of a volume from its METS file.
+type METS struct {
+	// Pages are sorted by their order in the physical structure of the
+	// volume.
+	Pages []Page
+	// byID maps IDs of pages to their indices.
+	byID map[string]int
+}
+
+// mets is the part of a METS file that describes texts of pages.
+type mets struct {
+	FileGrps []struct {
+		Use   string `xml:"USE,attr"`
+		Files []struct {
+			ID           string `xml:"ID,attr"`
+			Seq          string `xml:"SEQ,attr"`
+			Size         int64  `xml:"SIZE,attr"`
+			Checksum     string `xml:"CHECKSUM,attr"`
+			ChecksumType string `xml:"CHECKSUMTYPE,attr"`
+			FLocat       struct {
+				Href string `xml:"href,attr"`
+			} `xml:"FLocat"`
+		} `xml:"file"`
+	} `xml:"fileSec>fileGrp"`
+	StructMaps []struct {
+		Type string `xml:"TYPE,attr"`
+		Divs []div  `xml:"div"`
+	} `xml:"structMap"`
+}
+
+// div is a division of the structure of a volume. Divisions of pages
+// point to their files.
+type div struct {
+	Order      string `xml:"ORDER,attr"`
+	OrderLabel string `xml:"ORDERLABEL,attr"`
+	Label      string `xml:"LABEL,attr"`
+	Fptrs      []struct {
+		FileID string `xml:"FILEID,attr"`
+	} `xml:"fptr"`
+	Divs []div `xml:"div"`
+}
+
+// Path returns the path to the METS file of a volume from the path to its
+// zip file.
+func Path(zipPath string) string {
+	return strings.TrimSuffix(zipPath, filepath.Ext(zipPath)) + Ext
+}
+
+// Load reads a METS file.
+func Load(path string) (*METS, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	m, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read METS file %s: %s", path, err)
+	}
+	return m, nil
+}
+
+// Read decodes METS data.
+func Read(r io.Reader) (*METS, error) {
+	var data mets
+	if err := xml.NewDecoder(r).Decode(&data); err != nil {
+		return nil, err
+	}
+	res := &METS{byID: make(map[string]int)}
+	files := make(map[string]int)
+	for _, g := range data.FileGrps {
+		if g.Use != "ocr" {
+			continue
+		}
+		for _, f := range g.Files {
+			file := path.Base(f.FLocat.Href)
+			p := Page{
+				ID:           strings.TrimSuffix(file, path.Ext(file)),
+				File:         file,
+				Size:         f.Size,
+				Checksum:     strings.ToLower(f.Checksum),
+				ChecksumType: f.ChecksumType,
+			}
+			p.Sequence, _ = strconv.Atoi(f.Seq)
+			files[f.ID] = len(res.Pages)
+			res.Pages = append(res.Pages, p)
+		}
+	}
+	for _, sm := range data.StructMaps {
+		if sm.Type != "" && !strings.EqualFold(sm.Type, "physical") {
+			continue
+		}
+		res.addDivs(sm.Divs, files)
+	}
+	sort.SliceStable(res.Pages, func(i, j int) bool {
+		return res.Pages[i].Sequence < res.Pages[j].Sequence
+	})
+	for i, p := range res.Pages {
+		res.byID[p.ID] = i
+	}
+	return res, nil
+}
+
+// addDivs takes order and labels of pages from divisions of the physical
+// structure. Files maps IDs of files to indices of pages.
+func (m *METS) addDivs(divs []div, files map[string]int) {
+	for _, d := range divs {
+		for _, fp := range d.Fptrs {
+			i, ok := files[fp.FileID]
+			if !ok {
+				continue
+			}
+			p := &m.Pages[i]
+			if order, err := strconv.Atoi(d.Order); err == nil {
+				p.Sequence = order
+			}
+			p.OrderLabel = d.OrderLabel
+			p.Label = d.Label
+		}
+		m.addDivs(d.Divs, files)
+	}
+}
+
+// Page returns a page by its ID. It returns false if the METS file does not
+// describe the page.
+func (m *METS) Page(id string) (Page, bool) {
+	i, ok := m.byID[id]
+	if !ok {
+		return Page{}, false
+	}
+	return m.Pages[i], true
+}
+
+// Verify compares the checksum and the size of a page with its text. It
+// returns an error if they do not match, or if the type of the checksum is
+// not supported. Pages without a checksum are not verified.
+func (p Page) Verify(text []byte) error {
+	if p.Size > 0 && p.Size != int64(len(text)) {
+		return fmt.Errorf("size mismatch for %s: %d bytes instead of %d",
+			p.File, len(text), p.Size)
+	}
+	if p.Checksum == "" {
+		return nil
+	}
+	var h hash.Hash
+	switch strings.ToUpper(p.ChecksumType) {
+	case "MD5":
+		h = md5.New()
+	case "SHA-1":
+		h = sha1.New()
+	case "SHA-256":
+		h = sha256.New()
+	default:
+		return fmt.Errorf("unsupported checksum type '%s' for %s",
+			p.ChecksumType, p.File)
+	}
+	h.Write(text)
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != p.Checksum {
+		return fmt.Errorf("checksum mismatch for %s: %s instead of %s",
+			p.File, sum, p.Checksum)
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package mets_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestMets(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Mets Suite")
+}
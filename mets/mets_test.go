@@ -0,0 +1,85 @@
+package mets_test
+
+import (
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	. "github.com/gnames/htindex/mets"
+)
+
+var _ = Describe("Mets", func() {
+	Describe("Path", func() {
+		It("finds METS file of a volume", func() {
+			Expect(Path("mdp/pairtree_root/39/01/5/39015/39015.zip")).
+				To(Equal("mdp/pairtree_root/39/01/5/39015/39015.mets.xml"))
+		})
+	})
+
+	Describe("Load", func() {
+		It("reads pages from a METS file", func() {
+			m, err := Load(filepath.Join("testdata", "39000000000001.mets.xml"))
+			Expect(err).To(BeNil())
+			Expect(len(m.Pages)).To(Equal(4))
+			p, ok := m.Page("00000002")
+			Expect(ok).To(BeTrue())
+			Expect(p).To(Equal(Page{
+				ID:           "00000002",
+				File:         "00000002.txt",
+				Sequence:     2,
+				OrderLabel:   "1",
+				Size:         159,
+				Checksum:     "a40f4b2179cfe3a04f0eb94c14e8326405cfaf482e2c3d071a52eb59b86a37c7",
+				ChecksumType: "SHA-256",
+			}))
+			Expect(m.Pages[0].Label).To(Equal("FRONT_COVER"))
+			Expect(m.Pages[0].OrderLabel).To(BeEmpty())
+			_, ok = m.Page("00000001.jp2")
+			Expect(ok).To(BeFalse())
+		})
+
+		It("sorts pages by their order", func() {
+			m, err := Read(strings.NewReader(`<mets>
+				<fileSec><fileGrp USE="ocr">
+					<file ID="T2" SEQ="00000002"><FLocat href="00000002.txt"/></file>
+					<file ID="T1" SEQ="00000001"><FLocat href="00000001.txt"/></file>
+				</fileGrp></fileSec>
+				<structMap><div ORDER="1" ORDERLABEL="5"><fptr FILEID="T1"/></div></structMap>
+			</mets>`))
+			Expect(err).To(BeNil())
+			Expect(m.Pages[0].ID).To(Equal("00000001"))
+			Expect(m.Pages[0].OrderLabel).To(Equal("5"))
+			Expect(m.Pages[1].ID).To(Equal("00000002"))
+		})
+
+		It("fails on broken or missing files", func() {
+			_, err := Load(filepath.Join("testdata", "broken.mets.xml"))
+			Expect(err).ToNot(BeNil())
+			_, err = Load(filepath.Join("testdata", "nofile.mets.xml"))
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	DescribeTable("Verify",
+		func(p Page, text string, ok bool) {
+			p.File = "00000001.txt"
+			err := p.Verify([]byte(text))
+			Expect(err == nil).To(Equal(ok))
+		},
+		Entry("MD5", Page{Checksum: "900150983cd24fb0d6963f7d28e17f72",
+			ChecksumType: "MD5"}, "abc", true),
+		Entry("SHA-1", Page{Checksum: "a9993e364706816aba3e25717850c26c9cd0d89d",
+			ChecksumType: "SHA-1"}, "abc", true),
+		Entry("SHA-256", Page{Checksum: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+			ChecksumType: "SHA-256", Size: 3}, "abc", true),
+		Entry("wrong checksum", Page{Checksum: "900150983cd24fb0d6963f7d28e17f72",
+			ChecksumType: "MD5"}, "abd", false),
+		Entry("wrong size", Page{Size: 4}, "abc", false),
+		Entry("unknown type", Page{Checksum: "abc", ChecksumType: "CRC32"},
+			"abc", false),
+		Entry("no checksum", Page{}, "abc", true),
+	)
+})
@@ -0,0 +1,393 @@
+// Package nameindex creates an inverted index of canonical names from
+// results of htindex and answers queries about titles and pages where
+// a name was found.
+//
+// The index is a text file where every line contains a canonical name and
+// its occurrences in JSON, separated by a tab. Lines are sorted by names,
+// so a query needs only a binary search over the file and does not load
+// the index into memory. The same way, a file sorted by IDs of titles
+// connects every title to names found in it.
+package nameindex
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gnames/htindex/parser"
+)
+
+// File is the name of the index file in htindex output directory.
+const File = "names.idx"
+
+// TitlesFile is the name of the index of names by titles in htindex output
+// directory.
+const TitlesFile = "titles.idx"
+
+// Entry describes occurrences of a canonical name in the corpus.
+type Entry struct {
+	// Name is the canonical form of a name.
+	Name string `json:"name"`
+	// Count is the number of the name occurrences.
+	Count int `json:"count"`
+	// Titles contain occurrences of the name in titles.
+	Titles []Title `json:"titles"`
+}
+
+// Title describes occurrences of a name in a title.
+type Title struct {
+	// ID is HathiTrust ID of the title.
+	ID string `json:"id"`
+	// Count is the number of the name occurrences in the title.
+	Count int `json:"count"`
+	// Pages contain occurrences of the name in pages of the title.
+	Pages []Page `json:"pages"`
+}
+
+// TitleEntry describes names found in a title.
+type TitleEntry struct {
+	// ID is HathiTrust ID of the title.
+	ID string `json:"id"`
+	// Names contain names found in the title, the most frequent names go
+	// first.
+	Names []TitleName `json:"names"`
+}
+
+// TitleName describes occurrences of a name in a title.
+type TitleName struct {
+	// Name is the canonical form of the name.
+	Name string `json:"name"`
+	// Count is the number of the name occurrences in the title.
+	Count int `json:"count"`
+	// Pages contain pages of the title where the name was found.
+	Pages []Page `json:"pages"`
+}
+
+// Page describes occurrences of a name on a page.
+type Page struct {
+	// ID is the ID of the page.
+	ID string `json:"id"`
+	// Count is the number of the name occurrences on the page.
+	Count int `json:"count"`
+}
+
+// Build creates the index from results.csv located in the output
+// directory, and saves it to the same directory together with the index
+// of names by titles. It returns the number of indexed canonical names.
+func Build(dir string) (int, error) {
+	names, err := collect(filepath.Join(dir, "results.csv"))
+	if err != nil {
+		return 0, err
+	}
+	keys := make([]string, 0, len(names))
+	for k := range names {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	titles := make(map[string]*TitleEntry)
+	err = writeIndex(filepath.Join(dir, File), keys,
+		func(k string) interface{} {
+			e := newEntry(k, names[k])
+			addTitleNames(titles, e)
+			return e
+		})
+	if err != nil {
+		return 0, err
+	}
+
+	ids := make([]string, 0, len(titles))
+	for k := range titles {
+		ids = append(ids, k)
+	}
+	sort.Strings(ids)
+	err = writeIndex(filepath.Join(dir, TitlesFile), ids,
+		func(k string) interface{} {
+			t := titles[k]
+			sort.SliceStable(t.Names, func(i, j int) bool {
+				return t.Names[i].Count > t.Names[j].Count
+			})
+			return t
+		})
+	return len(keys), err
+}
+
+// writeIndex saves values returned for sorted keys to an index file.
+func writeIndex(path string, keys []string,
+	value func(string) interface{}) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, k := range keys {
+		if err = writeLine(w, k, value(k)); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err = w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// addTitleNames adds occurrences of a name to entries of titles. Names
+// come in alphabetical order, so they stay sorted within a title.
+func addTitleNames(titles map[string]*TitleEntry, e *Entry) {
+	for _, t := range e.Titles {
+		te, ok := titles[t.ID]
+		if !ok {
+			te = &TitleEntry{ID: t.ID}
+			titles[t.ID] = te
+		}
+		te.Names = append(te.Names,
+			TitleName{Name: e.Name, Count: t.Count, Pages: t.Pages})
+	}
+}
+
+// Lookup finds a name in the index located in the output directory. The
+// name is converted to its canonical form before the search. If the name
+// is not in the index, Lookup returns nil.
+func Lookup(dir, name string) (*Entry, error) {
+	key := parser.New().Canonical(normalize(name))
+	line, err := find(filepath.Join(dir, File), key)
+	if err != nil || line == nil {
+		return nil, err
+	}
+	e := &Entry{}
+	return e, parseLine(line, e)
+}
+
+// LookupTitle finds names of a title in the index of names by titles
+// located in the output directory. If the title is not in the index,
+// LookupTitle returns nil.
+func LookupTitle(dir, id string) (*TitleEntry, error) {
+	line, err := find(filepath.Join(dir, TitlesFile), id)
+	if err != nil || line == nil {
+		return nil, err
+	}
+	t := &TitleEntry{}
+	return t, parseLine(line, t)
+}
+
+// find searches an index file for the line with a given key. It returns
+// nil if there is no such line.
+func find(path, key string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return search(f, stat.Size(), key)
+}
+
+// Each feeds all entries of the index located in the output directory to
+// a given function, in the order of names.
+func Each(dir string, fn func(*Entry) error) error {
+	f, err := os.Open(filepath.Join(dir, File))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			return nil
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		e := &Entry{}
+		if err = parseLine(bytes.TrimRight(line, "\n"), e); err != nil {
+			return err
+		}
+		if err = fn(e); err != nil {
+			return err
+		}
+	}
+}
+
+// counts keeps the number of occurrences of a name per title and page.
+type counts map[string]map[string]int
+
+// collect reads results and counts occurrences of canonical names. If
+// results do not have canonical forms, name-strings are parsed.
+func collect(path string) (map[string]counts, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.ReuseRecord = true
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read header of %s: %s", path, err)
+	}
+	idx := make(map[string]int)
+	for i, v := range header {
+		idx[v] = i
+	}
+	for _, k := range []string{"ID", "PageID", "NameString"} {
+		if _, ok := idx[k]; !ok {
+			return nil, fmt.Errorf("field %s is missing in %s", k, path)
+		}
+	}
+
+	p := parser.New()
+	canonicals := make(map[string]string)
+	res := make(map[string]counts)
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			return res, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var can string
+		if i, ok := idx["Canonical"]; ok {
+			can = row[i]
+		}
+		if can == "" {
+			name := row[idx["NameString"]]
+			var ok bool
+			if can, ok = canonicals[name]; !ok {
+				can = p.Canonical(normalize(name))
+				canonicals[name] = can
+			}
+		}
+		c, ok := res[can]
+		if !ok {
+			c = make(counts)
+			res[can] = c
+		}
+		titleID := row[idx["ID"]]
+		if _, ok = c[titleID]; !ok {
+			c[titleID] = make(map[string]int)
+		}
+		c[titleID][row[idx["PageID"]]]++
+	}
+}
+
+// normalize removes whitespace characters that would break the index
+// format.
+func normalize(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+func newEntry(name string, c counts) *Entry {
+	e := &Entry{Name: name, Titles: make([]Title, 0, len(c))}
+	for id, pages := range c {
+		t := Title{ID: id, Pages: make([]Page, 0, len(pages))}
+		for pageID, num := range pages {
+			t.Pages = append(t.Pages, Page{ID: pageID, Count: num})
+			t.Count += num
+		}
+		sort.Slice(t.Pages, func(i, j int) bool {
+			return t.Pages[i].ID < t.Pages[j].ID
+		})
+		e.Titles = append(e.Titles, t)
+		e.Count += t.Count
+	}
+	sort.Slice(e.Titles, func(i, j int) bool {
+		if e.Titles[i].Count == e.Titles[j].Count {
+			return e.Titles[i].ID < e.Titles[j].ID
+		}
+		return e.Titles[i].Count > e.Titles[j].Count
+	})
+	return e
+}
+
+// writeLine saves a key and its value in JSON as a line of an index.
+func writeLine(w io.Writer, key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\t%s\n", key, data)
+	return err
+}
+
+// search finds the line of the index that starts with a given key, using
+// a binary search over byte offsets of the file. It returns nil if there
+// is no such line.
+func search(r io.ReaderAt, size int64, name string) ([]byte, error) {
+	lo, hi := int64(0), size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		line, err := lineAfter(r, size, mid)
+		if err != nil {
+			return nil, err
+		}
+		if line == nil || key(line) >= name {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	line, err := lineAfter(r, size, lo)
+	if err != nil || line == nil || key(line) != name {
+		return nil, err
+	}
+	return line, nil
+}
+
+// lineAfter returns the first line of the index that starts at the offset
+// or after it. It returns nil if there is no such line.
+func lineAfter(r io.ReaderAt, size, offset int64) ([]byte, error) {
+	if offset > 0 {
+		offset--
+	}
+	br := bufio.NewReader(io.NewSectionReader(r, offset, size-offset))
+	if offset > 0 {
+		if _, err := br.ReadBytes('\n'); err != nil {
+			if err == io.EOF {
+				return nil, nil
+			}
+			return nil, err
+		}
+	}
+	line, err := br.ReadBytes('\n')
+	if err == io.EOF {
+		if len(line) == 0 {
+			return nil, nil
+		}
+		err = nil
+	}
+	return bytes.TrimRight(line, "\n"), err
+}
+
+// parseLine converts the value of a line of an index from JSON.
+func parseLine(line []byte, v interface{}) error {
+	i := bytes.IndexByte(line, '\t')
+	if i < 0 {
+		return fmt.Errorf("broken line in index: '%s'", line)
+	}
+	return json.Unmarshal(line[i+1:], v)
+}
+
+func key(line []byte) string {
+	i := bytes.IndexByte(line, '\t')
+	if i < 0 {
+		return string(line)
+	}
+	return string(line[:i])
+}
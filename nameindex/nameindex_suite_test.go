@@ -0,0 +1,13 @@
+package nameindex_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestNameindex(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Nameindex Suite")
+}
@@ -0,0 +1,94 @@
+package nameindex_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/gnames/htindex/nameindex"
+)
+
+var _ = Describe("Nameindex", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "htindex-nameindex")
+		Expect(err).To(BeNil())
+		data, err := ioutil.ReadFile(filepath.Join("testdata", "results.csv"))
+		Expect(err).To(BeNil())
+		err = ioutil.WriteFile(filepath.Join(dir, "results.csv"), data, 0644)
+		Expect(err).To(BeNil())
+		num, err := nameindex.Build(dir)
+		Expect(err).To(BeNil())
+		Expect(num).To(Equal(5))
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("finds titles and pages of a name", func() {
+		e, err := nameindex.Lookup(dir, "Pomatomus saltatrix")
+		Expect(err).To(BeNil())
+		Expect(e.Name).To(Equal("Pomatomus saltatrix"))
+		Expect(e.Count).To(Equal(4))
+		Expect(len(e.Titles)).To(Equal(2))
+		t := e.Titles[0]
+		Expect(t.ID).To(Equal("mdp.39015027528705"))
+		Expect(t.Count).To(Equal(3))
+		Expect(t.Pages).To(Equal([]nameindex.Page{
+			{ID: "00000014", Count: 2}, {ID: "00000020", Count: 1},
+		}))
+		Expect(e.Titles[1].ID).To(Equal("uc2.ark:/13960/t6154rj46"))
+	})
+
+	DescribeTable("Lookup",
+		func(name, canonical string, count int) {
+			e, err := nameindex.Lookup(dir, name)
+			Expect(err).To(BeNil())
+			if canonical == "" {
+				Expect(e).To(BeNil())
+				return
+			}
+			Expect(e.Name).To(Equal(canonical))
+			Expect(e.Count).To(Equal(count))
+		},
+		Entry("first", "Abies", "Abies", 1),
+		Entry("last", "Zea mays", "Zea mays", 1),
+		Entry("authorship", "Bubo bubo (Linnaeus, 1758)", "Bubo bubo", 1),
+		Entry("rank", "Quercus alba major", "Quercus alba major", 1),
+		Entry("spaces", " Zea   mays ", "Zea mays", 1),
+		Entry("before all", "Aaa", "", 0),
+		Entry("after all", "Zzz", "", 0),
+		Entry("in between", "Pomatomus", "", 0),
+	)
+
+	It("finds names of a title", func() {
+		t, err := nameindex.LookupTitle(dir, "mdp.39015027528705")
+		Expect(err).To(BeNil())
+		Expect(t.ID).To(Equal("mdp.39015027528705"))
+		Expect(t.Names[0]).To(Equal(nameindex.TitleName{
+			Name:  "Pomatomus saltatrix",
+			Count: 3,
+			Pages: []nameindex.Page{
+				{ID: "00000014", Count: 2}, {ID: "00000020", Count: 1},
+			},
+		}))
+		for i := 1; i < len(t.Names); i++ {
+			Expect(t.Names[i].Count).To(BeNumerically("<=", t.Names[i-1].Count))
+		}
+		t, err = nameindex.LookupTitle(dir, "nope")
+		Expect(err).To(BeNil())
+		Expect(t).To(BeNil())
+	})
+
+	It("returns an error if index does not exist", func() {
+		_, err := nameindex.Lookup(filepath.Join(dir, "nodir"), "Bubo bubo")
+		Expect(err).ToNot(BeNil())
+	})
+})
@@ -0,0 +1,199 @@
+package htindex
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/gnames/gnfinder"
+	"github.com/gnames/gnfinder/output"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalization is a set of steps of OCR text cleaning that are done
+// before name-finding.
+type Normalization int
+
+// Steps of normalization.
+const (
+	// NormUnicode converts texts to Unicode normalization form C, so letters
+	// with diacritics are single characters.
+	NormUnicode Normalization = 1 << iota
+	// NormLigatures expands typographic ligatures like 'ﬁ' to separate
+	// letters.
+	NormLigatures
+	// NormHeaders removes running headers and footers, that are lines
+	// repeated at the top or the bottom of many pages, including page
+	// numbers.
+	NormHeaders
+	// NormHyphens joins words split by hyphens at the ends of lines and
+	// removes soft hyphens.
+	NormHyphens
+)
+
+// NormAll contains all steps of normalization.
+const NormAll = NormUnicode | NormLigatures | NormHeaders | NormHyphens
+
+// normSteps contains names of normalization steps.
+var normSteps = []struct {
+	n    Normalization
+	name string
+}{
+	{NormUnicode, "unicode"},
+	{NormLigatures, "ligatures"},
+	{NormHeaders, "headers"},
+	{NormHyphens, "hyphens"},
+}
+
+func (n Normalization) String() string {
+	var res []string
+	for _, v := range normSteps {
+		if n&v.n != 0 {
+			res = append(res, v.name)
+		}
+	}
+	return strings.Join(res, ",")
+}
+
+// NewNormalization takes names of normalization steps and returns
+// matching Normalization, or an error if some step does not exist. The
+// name 'all' means all steps.
+func NewNormalization(steps []string) (Normalization, error) {
+	var res Normalization
+	for _, s := range steps {
+		if s == "all" {
+			res |= NormAll
+			continue
+		}
+		var found bool
+		for _, v := range normSteps {
+			if v.name == s {
+				res |= v.n
+				found = true
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unknown normalization step '%s'", s)
+		}
+	}
+	return res, nil
+}
+
+// ligatures contain expansions of typographic ligatures.
+var ligatures = map[rune]string{
+	'\ufb00': "ff",
+	'\ufb01': "fi",
+	'\ufb02': "fl",
+	'\ufb03': "ffi",
+	'\ufb04': "ffl",
+	'\ufb05': "st",
+	'\ufb06': "st",
+}
+
+// prepared is a text of a page prepared for name-finding.
+type prepared struct {
+	text []rune
+	// m maps positions of text to the original text of the page.
+	m offsetMap
+	// size is the number of runes in the original text.
+	size int
+}
+
+// prepareTexts normalizes texts of pages according to the Normalization
+// setting. Headers are running headers and footers of pages.
+func (hti *HTindex) prepareTexts(texts [][]rune,
+	headers [][]lineRange) []prepared {
+	res := make([]prepared, len(texts))
+	for i, text := range texts {
+		p := prepared{text: text, m: identityMap(i, len(text)), size: len(text)}
+		if hti.Normalization&NormHeaders != 0 {
+			p.text, p.m = removeLines(p.text, p.m, headers[i])
+		}
+		if hti.Normalization&(NormUnicode|NormLigatures) != 0 {
+			p.text, p.m = hti.normalizeChars(p.text, p.m)
+		}
+		if hti.Normalization&NormHyphens != 0 {
+			p.text, p.m = dehyphenate(p.text, p.m)
+		}
+		res[i] = p
+	}
+	return res
+}
+
+// findNames finds names in a prepared text of a page. Offsets of found
+// names refer to the original text.
+func (p prepared) findNames(gnf *gnfinder.GNfinder,
+	opts ...gnfinder.Option) *output.Output {
+	res := gnf.FindNames([]byte(string(p.text)), opts...)
+	for i := range res.Names {
+		n := &res.Names[i]
+		_, n.OffsetStart = p.m.locate(n.OffsetStart)
+		_, n.OffsetEnd = p.m.locateEnd(n.OffsetEnd)
+	}
+	return res
+}
+
+// removeLines removes given lines from a text. Line breaks stay, so the
+// text keeps its layout.
+func removeLines(text []rune, m offsetMap, lines []lineRange) ([]rune,
+	offsetMap) {
+	if len(lines) == 0 {
+		return text, m
+	}
+	b := textBuilder{text: make([]rune, 0, len(text))}
+	var from int
+	for _, l := range lines {
+		b.copyText(text, m, from, l.start)
+		from = l.end
+	}
+	b.copyText(text, m, from, len(text))
+	return b.text, b.m
+}
+
+// normalizeChars converts a text to Unicode normalization form C and
+// expands ligatures, according to the Normalization setting. A letter is
+// normalized together with combining marks that follow it.
+func (hti *HTindex) normalizeChars(text []rune, m offsetMap) ([]rune,
+	offsetMap) {
+	b := textBuilder{text: make([]rune, 0, len(text))}
+	var from int
+	for i := 0; i < len(text); {
+		j := i + 1
+		for j < len(text) && unicode.Is(unicode.Mn, text[j]) {
+			j++
+		}
+		if rs, ok := hti.normalizeChar(text[i:j]); ok {
+			b.copyText(text, m, from, i)
+			page, start := m.locate(i)
+			_, end := m.locateEnd(j)
+			b.replace(rs, page, start, end-start)
+			from = j
+		}
+		i = j
+	}
+	b.copyText(text, m, from, len(text))
+	return b.text, b.m
+}
+
+// normalizeChar normalizes a letter with its combining marks. It returns
+// false if normalization does not change them.
+func (hti *HTindex) normalizeChar(rs []rune) ([]rune, bool) {
+	if len(rs) == 1 && rs[0] < unicode.MaxLatin1 {
+		return nil, false
+	}
+	var changed bool
+	if hti.Normalization&NormUnicode != 0 {
+		s := string(rs)
+		if !norm.NFC.IsNormalString(s) {
+			rs = []rune(norm.NFC.String(s))
+			changed = true
+		}
+	}
+	if hti.Normalization&NormLigatures != 0 {
+		if l, ok := ligatures[rs[0]]; ok {
+			rs = append([]rune(l), rs[1:]...)
+			changed = true
+		}
+	}
+	return rs, changed
+}
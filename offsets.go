@@ -0,0 +1,177 @@
+package htindex
+
+import (
+	"sort"
+	"unicode"
+)
+
+// softHyphen marks a possible hyphenation point in a word, it is not
+// visible in printed texts.
+const softHyphen = '\u00ad'
+
+// offsetMap maps positions of a text prepared for name-finding to pages
+// and positions of original texts. Positions are counted in runes, as
+// offsets of gnfinder. Spans of the map cover the whole prepared text.
+type offsetMap []span
+
+// span is a part of a prepared text that came from a part of a page text.
+// Usually the part is copied without changes and both have the same size.
+// Otherwise it is a replacement, for example a ligature that was expanded,
+// and positions inside of it cannot be mapped exactly.
+type span struct {
+	// start is the position of the span in the prepared text.
+	start int
+	// size is the number of runes of the span in the prepared text.
+	size int
+	// page is the index of the page.
+	page int
+	// pageStart is the position of the span in the text of the page.
+	pageStart int
+	// pageSize is the number of runes of the span in the text of the page.
+	pageSize int
+}
+
+// copied is true if the span is an unchanged copy of the page text.
+func (s span) copied() bool {
+	return s.size == s.pageSize
+}
+
+// add appends a span to the map. Copies of adjacent parts of a page are
+// merged together.
+func (m *offsetMap) add(s span) {
+	if l := len(*m); l > 0 {
+		last := &(*m)[l-1]
+		if last.copied() && s.copied() && last.page == s.page &&
+			last.start+last.size == s.start &&
+			last.pageStart+last.pageSize == s.pageStart {
+			last.size += s.size
+			last.pageSize += s.pageSize
+			return
+		}
+	}
+	*m = append(*m, s)
+}
+
+// find returns the index of the span that contains a position of the
+// prepared text.
+func (m offsetMap) find(pos int) int {
+	i := sort.Search(len(m), func(i int) bool { return m[i].start > pos }) - 1
+	if i < 0 {
+		return 0
+	}
+	return i
+}
+
+// locate returns the index of a page and the position in its text for a
+// position of the prepared text.
+func (m offsetMap) locate(pos int) (int, int) {
+	s := m[m.find(pos)]
+	if !s.copied() {
+		return s.page, s.pageStart
+	}
+	return s.page, s.pageStart + pos - s.start
+}
+
+// locateEnd returns the index of a page and the end position in its text
+// for the end of a part of the prepared text.
+func (m offsetMap) locateEnd(end int) (int, int) {
+	s := m[m.find(end-1)]
+	if !s.copied() {
+		return s.page, s.pageStart + s.pageSize
+	}
+	return s.page, s.pageStart + end - s.start
+}
+
+// textBuilder creates a prepared text together with its offsetMap.
+type textBuilder struct {
+	text []rune
+	m    offsetMap
+}
+
+// add appends a rune from the position pagePos of a page.
+func (b *textBuilder) add(r rune, page, pagePos int) {
+	b.m.add(span{start: len(b.text), size: 1, page: page,
+		pageStart: pagePos, pageSize: 1})
+	b.text = append(b.text, r)
+}
+
+// replace appends runes that replace pageSize runes of a page text
+// starting at pagePos.
+func (b *textBuilder) replace(rs []rune, page, pagePos, pageSize int) {
+	if len(rs) == 0 {
+		return
+	}
+	b.m.add(span{start: len(b.text), size: len(rs), page: page,
+		pageStart: pagePos, pageSize: pageSize})
+	b.text = append(b.text, rs...)
+}
+
+// copyText appends text[from:to] of a prepared text with the offsetMap m.
+func (b *textBuilder) copyText(text []rune, m offsetMap, from, to int) {
+	for i, pos := m.find(from), from; pos < to; i++ {
+		s := m[i]
+		end := s.start + s.size
+		if end > to {
+			end = to
+		}
+		ns := span{start: len(b.text), size: end - pos, page: s.page,
+			pageStart: s.pageStart, pageSize: s.pageSize}
+		if s.copied() {
+			ns.pageStart += pos - s.start
+			ns.pageSize = ns.size
+		}
+		b.m.add(ns)
+		b.text = append(b.text, text[pos:end]...)
+		pos = end
+	}
+}
+
+// identityMap maps a page text to itself.
+func identityMap(page, size int) offsetMap {
+	if size == 0 {
+		return nil
+	}
+	return offsetMap{{size: size, page: page, pageSize: size}}
+}
+
+// dehyphenate joins parts of words that are split by hyphens at the ends
+// of lines, and removes soft hyphens. It returns the new text and its
+// offsetMap to the original texts.
+func dehyphenate(text []rune, m offsetMap) ([]rune, offsetMap) {
+	b := textBuilder{text: make([]rune, 0, len(text))}
+	var from int
+	for i := 0; i < len(text); i++ {
+		if j := hyphenEnd(text, i); j > i {
+			b.copyText(text, m, from, i)
+			from = j
+			i = j - 1
+		}
+	}
+	b.copyText(text, m, from, len(text))
+	return b.text, b.m
+}
+
+// hyphenEnd returns the end of a hyphenation that starts at the position
+// i, or i if there is no hyphenation. A hyphenation is a hyphen between
+// a letter and a line break followed by a lowercase letter. A soft hyphen
+// does not need a line break.
+func hyphenEnd(text []rune, i int) int {
+	r := text[i]
+	if (r != '-' && r != softHyphen) || i == 0 || !unicode.IsLetter(text[i-1]) {
+		return i
+	}
+	j := i + 1
+	var lineBreak bool
+	for ; j < len(text) && unicode.IsSpace(text[j]); j++ {
+		if text[j] == '\n' {
+			lineBreak = true
+		}
+	}
+	if j == len(text) || !unicode.IsLower(text[j]) {
+		return i
+	}
+	if !lineBreak && r != softHyphen {
+		return i
+	}
+	return j
+}
@@ -1,132 +1,460 @@
 package htindex
 
 import (
-	"encoding/csv"
 	"log"
-	"os"
-	"path/filepath"
+	"sort"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
-
-	"github.com/gnames/gnfinder/output"
 )
 
-// detectedName holds information about a name-string returned by a
+// Sink receives results of name-finding and saves them to some storage.
+// HTindex never calls methods of a Sink concurrently.
+type Sink interface {
+	// WriteTitle saves metadata of a processed title.
+	WriteTitle(t *Title) error
+	// WriteOccurrences saves names found in a title. It is called after
+	// WriteTitle for every title, even if no names were found.
+	WriteOccurrences(titleID string, occs []Occurrence) error
+	// WriteError saves an error that happened during processing.
+	WriteError(e *Error) error
+	// Close finishes writing and releases resources.
+	Close() error
+}
+
+// SummaryWriter is an optional interface of a Sink. Sinks that implement
+// it receive a summary of names found in every title. WriteSummary is
+// called after WriteTitle and before WriteOccurrences of the same title.
+type SummaryWriter interface {
+	WriteSummary(titleID string, sum []NameSummary) error
+}
+
+// PageWriter is an optional interface of a Sink. Sinks that implement it
+// receive metadata of all pages of every title. WritePages is called after
+// WriteSummary and before WriteOccurrences of the same title.
+type PageWriter interface {
+	WritePages(titleID string, pages []Page) error
+}
+
+// Title contains metadata of a processed title.
+type Title struct {
+	// ID is HathiTrust ID of the title.
+	ID string `json:"id"`
+	// SHA256 is a checksum of the title's zip file.
+	SHA256 string `json:"sha256"`
+	// Path is a path to the zip file relative to the root directory.
+	Path string `json:"path"`
+	// PagesNumber is the number of pages in the title.
+	PagesNumber int `json:"pagesNumber"`
+	// BadPagesNumber is the number of pages with non-standard file names.
+	BadPagesNumber int `json:"badPagesNumber"`
+	// NamesOccurrences is the number of names found in the title.
+	NamesOccurrences int `json:"namesOccurrences"`
+	// Language is the ISO 639-3 code of the detected language of the title.
+	Language string `json:"language,omitempty"`
+	// Unchanged is true in incremental mode, if the title did not change
+	// since the previous run. Occurrences of such titles are copied from
+	// the previous output and are not sent to a Sink.
+	Unchanged bool `json:"unchanged,omitempty"`
+}
+
+// Occurrence holds information about a name-string returned by a
 // name-finder.
-type detectedName struct {
-	pageID      string
-	verbatim    string
-	nameString  string
-	offsetStart int
-	offsetEnd   int
-	wordsBefore string
-	wordsAfter  string
-	annotNomen  string
-	odds        float64
-	kind        string
-	timestamp   string
+type Occurrence struct {
+	// TimeStamp is the time of the output in nanoseconds from epoch.
+	TimeStamp int64 `json:"timeStamp"`
+	// PageID is the ID of a page where the name was found.
+	PageID string `json:"pageId"`
+	// Verbatim is the name as it appears in the text.
+	Verbatim string `json:"verbatim"`
+	// NameString is a normalized version of the name.
+	NameString string `json:"nameString"`
+	// OffsetStart is the start of the name on the page.
+	OffsetStart int `json:"offsetStart"`
+	// OffsetEnd is the end of the name on the page. If the name continues
+	// on the next page, it is the end on that page.
+	OffsetEnd int `json:"offsetEnd"`
+	// WordsBefore are words that happened before the name.
+	WordsBefore []string `json:"wordsBefore"`
+	// WordsAfter are words that happened after the name.
+	WordsAfter []string `json:"wordsAfter"`
+	// AnnotNomen is a nomenclatural annotation like 'sp. nov.'.
+	AnnotNomen string `json:"annotNomen,omitempty"`
+	// Odds show a probability that name detection was correct.
+	Odds float64 `json:"odds"`
+	// ConfidenceClass is a class of odds: high, medium, low, or unknown
+	// when Bayes odds were not calculated.
+	ConfidenceClass string `json:"confidenceClass"`
+	// Kind is the type of name-finding decision.
+	Kind string `json:"kind"`
+	// Canonical is the simple canonical form of the name-string, it
+	// allows to group together names with and without authors.
+	Canonical string `json:"canonical"`
+	// CanonicalFull is the canonical form with infraspecific ranks.
+	CanonicalFull string `json:"canonicalFull"`
+	// Cardinality is the number of elements in the name, 0 if unknown.
+	Cardinality int `json:"cardinality"`
+	// Quality is the quality of parsing, 0 if the name was not parsed.
+	Quality int `json:"quality"`
+	// NameID is a UUID v5 of the name-string.
+	NameID string `json:"nameId"`
+	// Language is the ISO 639-3 code of the detected language of the page.
+	Language string `json:"language,omitempty"`
+	// MatchID is the ID of the matched name in a reference source.
+	MatchID string `json:"matchId,omitempty"`
+	// AcceptedName is the accepted name for the matched name.
+	AcceptedName string `json:"acceptedName,omitempty"`
+	// MatchType is the type of the match, for example Exact, Canonical or
+	// NoMatch. It is empty if names are not verified.
+	MatchType string `json:"matchType,omitempty"`
+	// DataSource is the title of the reference source of the match.
+	DataSource string `json:"dataSource,omitempty"`
+	// EndPageID is the ID of the page where the name ends, if it is not
+	// the page where the name starts. It is set only in stream mode.
+	EndPageID string `json:"endPageId,omitempty"`
+	// InHeader is true if the name is in a running header or footer, that
+	// is a line repeated at the top or the bottom of many pages.
+	InHeader bool `json:"inHeader,omitempty"`
+	// ExpandedName is the name with the full genus, if the genus of the
+	// name is abbreviated (like 'Q. alba') and it was mentioned in full
+	// earlier in the title.
+	ExpandedName string `json:"expandedName,omitempty"`
+	// ExpansionConfidence is a class of reliability of ExpandedName: high,
+	// medium or low.
+	ExpansionConfidence string `json:"expansionConfidence,omitempty"`
+	// PageLabel is the page number printed on the page where the name was
+	// found. It is taken from the METS file of the title.
+	PageLabel string `json:"pageLabel,omitempty"`
 }
 
-// outputError outputs errors arrived from the name-finding process.
-func (hti *HTindex) outputError(errCh <-chan *htiError, wgOut *sync.WaitGroup) {
-	f, err := os.Create(filepath.Join(hti.OutputPath, "errors.csv"))
-	defer wgOut.Done()
+// NameSummary describes occurrences of a name-string in a title.
+type NameSummary struct {
+	// NameString is a normalized version of the name.
+	NameString string `json:"nameString"`
+	// OccurrencesNumber is the number of the name occurrences in the title.
+	OccurrencesNumber int `json:"occurrencesNumber"`
+	// FirstPageID is the ID of the first page with the name.
+	FirstPageID string `json:"firstPageId"`
+	// LastPageID is the ID of the last page with the name.
+	LastPageID string `json:"lastPageId"`
+	// PagesNumber is the number of pages with the name.
+	PagesNumber int `json:"pagesNumber"`
+	// MaxOdds are the highest odds of the name occurrences.
+	MaxOdds float64 `json:"maxOdds"`
+}
+
+// Page contains metadata and OCR quality metrics of a page.
+type Page struct {
+	// ID is the ID of the page.
+	ID string `json:"id"`
+	// Sequence is the number of the page in the scanned title, taken from
+	// the name of the page file, or from the METS file of the title.
+	Sequence int `json:"sequence"`
+	// Label is the page number printed on the page, for example 'xii' or
+	// '27'. It is taken from the METS file of the title.
+	Label string `json:"label,omitempty"`
+	// NonStandardName is true if the name of the page file does not follow
+	// HathiTrust conventions.
+	NonStandardName bool `json:"nonStandardName,omitempty"`
+	// Language is the ISO 639-3 code of the detected language of the page.
+	Language string `json:"language,omitempty"`
+	// ByteSize is the size of the page text in bytes.
+	ByteSize int `json:"byteSize"`
+	// CharsNumber is the number of characters of the page text.
+	CharsNumber int `json:"charsNumber"`
+	// WordsNumber is the number of words of the page text.
+	WordsNumber int `json:"wordsNumber"`
+	// NamesNumber is the number of names found on the page.
+	NamesNumber int `json:"namesNumber"`
+	// AlphaRatio is the ratio of letters among characters that are not
+	// spaces.
+	AlphaRatio float64 `json:"alphaRatio"`
+	// DictWordsRatio is the ratio of dictionary words among words of the
+	// page. It is used as the quality of the page.
+	DictWordsRatio float64 `json:"dictWordsRatio"`
+	// AvgWordLength is the average length of words in characters.
+	AvgWordLength float64 `json:"avgWordLength"`
+	// Skipped is true if name-finding was skipped because of low quality
+	// of the page.
+	Skipped bool `json:"skipped"`
+}
+
+// Error describes a problem that happened during processing of a title.
+type Error struct {
+	// TimeStamp is the time of the error in nanoseconds from epoch.
+	TimeStamp int64 `json:"timeStamp"`
+	// TitleID is the ID of a title where the error happened.
+	TitleID string `json:"titleId,omitempty"`
+	// PageID is the ID of a page where the error happened.
+	PageID string `json:"pageId,omitempty"`
+	// Message describes the error.
+	Message string `json:"error"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// newSink creates a Sink. It is either the Sink provided by options, or
+// a Sink for the output format of HTindex.
+func (hti *HTindex) newSink() (Sink, error) {
+	var s Sink
+	var err error
+	switch {
+	case hti.Sink != nil:
+		s = hti.Sink
+	case hti.Format == Parquet:
+		s, err = hti.newParquetSink()
+	case hti.Format == JSONL:
+		s, err = hti.newJSONLSink()
+	case hti.Format == SQLite:
+		s, err = hti.newSQLiteSink()
+	default:
+		s, err = hti.newCSVSink()
+	}
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	ef := csv.NewWriter(f)
-	_ = ef.Write([]string{"TimeStamp", "TitleID", "PageID", "Error"})
-	defer f.Close()
-	defer ef.Flush()
+	return &syncSink{s: s}, nil
+}
+
+// syncSink allows to share a Sink between goroutines that output
+// errors and results.
+type syncSink struct {
+	sync.Mutex
+	s Sink
+}
+
+func (ss *syncSink) WriteTitle(t *Title) error {
+	ss.Lock()
+	defer ss.Unlock()
+	return ss.s.WriteTitle(t)
+}
+
+func (ss *syncSink) WriteOccurrences(titleID string, occs []Occurrence) error {
+	ss.Lock()
+	defer ss.Unlock()
+	return ss.s.WriteOccurrences(titleID, occs)
+}
+
+func (ss *syncSink) WriteSummary(titleID string, sum []NameSummary) error {
+	ss.Lock()
+	defer ss.Unlock()
+	if sw, ok := ss.s.(SummaryWriter); ok {
+		return sw.WriteSummary(titleID, sum)
+	}
+	return nil
+}
+
+func (ss *syncSink) WritePages(titleID string, pages []Page) error {
+	ss.Lock()
+	defer ss.Unlock()
+	if pw, ok := ss.s.(PageWriter); ok {
+		return pw.WritePages(titleID, pages)
+	}
+	return nil
+}
+
+func (ss *syncSink) WriteError(e *Error) error {
+	ss.Lock()
+	defer ss.Unlock()
+	return ss.s.WriteError(e)
+}
+
+func (ss *syncSink) Close() error {
+	ss.Lock()
+	defer ss.Unlock()
+	return ss.s.Close()
+}
+
+// outputError outputs errors arrived from the name-finding process.
+func (hti *HTindex) outputError(errCh <-chan *Error, s Sink,
+	wgOut *sync.WaitGroup) {
+	defer wgOut.Done()
 	for e := range errCh {
-		_ = ef.Write([]string{e.ts, e.titleID, e.pageID, e.msg})
+		if err := s.WriteError(e); err != nil {
+			log.Fatal(err)
+		}
 	}
 }
 
 // outputResults outputs data about found names.
-func (hti *HTindex) outputResult(outCh <-chan *title, wgOut *sync.WaitGroup) {
+func (hti *HTindex) outputResult(outCh <-chan *title, s Sink,
+	prev *previous, sc *statsCollector, wgOut *sync.WaitGroup) {
 	defer wgOut.Done()
 	count := 0
 	ts := time.Now()
 
-	f, err := os.Create(filepath.Join(hti.OutputPath, "results.csv"))
-	if err != nil {
-		log.Fatal(err)
-	}
-	titles, err := os.Create(filepath.Join(hti.OutputPath, "titles.csv"))
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	of := csv.NewWriter(f)
-	tf := csv.NewWriter(titles)
-	_ = of.Write([]string{
-		"TimeStamp", "ID", "PageID", "Verbatim", "WordsBefore", "NameString",
-		"WordsAfter", "AnnotNomen", "OffsetStart", "OffsetEnd", "Odds", "Kind",
-	})
-	_ = tf.Write([]string{"ID", "SHA256", "Path", "PagesNumber", "BadPagesNumber", "NamesOccurences"})
-
-	defer f.Close()
-	defer titles.Close()
-	defer of.Flush()
-	defer tf.Flush()
-
 	for t := range outCh {
-		_ = tf.Write([]string{
-			t.id, t.sha256, t.path, strconv.Itoa(len(t.pages)),
-			strconv.Itoa(t.pagesNumBadNames), strconv.Itoa(t.namesNum),
-		})
+		var occs []Occurrence
+		tExp := t.export()
+		if t.unchanged {
+			prev.unchanged[t.id] = struct{}{}
+			tExp = prev.title(t.id)
+		} else {
+			occs = t.occurrences()
+		}
+		sc.addTitle(tExp)
+		sc.addOccurrences(t.id, occs)
+		if err := s.WriteTitle(tExp); err != nil {
+			log.Fatal(err)
+		}
+		if sw, ok := s.(SummaryWriter); ok && !t.unchanged {
+			if err := sw.WriteSummary(t.id, summarize(occs)); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if pw, ok := s.(PageWriter); ok && !t.unchanged {
+			if err := pw.WritePages(t.id, t.pagesMeta()); err != nil {
+				log.Fatal(err)
+			}
+		}
 
 		count++
 		if hti.ProgressNum > 0 && count%hti.ProgressNum == 0 {
 			rate := float64(count) / (time.Since(ts).Minutes())
 			log.Printf("Processing %dth title. Rate %0.2f titles/min\n", count, rate)
 		}
-		if t.namesNum == 0 {
-			continue
+		if err := s.WriteOccurrences(t.id, occs); err != nil {
+			log.Fatal(err)
 		}
-		for _, p := range t.pages {
-			for _, name := range p.res.Names {
-				n := newDetectedName(p, name)
-				out := []string{
-					n.timestamp, t.id, n.pageID, n.verbatim, n.wordsBefore,
-					n.nameString, n.wordsAfter, n.annotNomen,
-					strconv.Itoa(n.offsetStart), strconv.Itoa(n.offsetEnd),
-					strconv.Itoa(int(n.odds)), n.kind,
-				}
-				_ = of.Write(out)
-
-				if err := of.Error(); err != nil {
-					log.Fatal(err)
-				}
-			}
+	}
+}
+
+// ts generates a timestamp in nanoseconds from epoch.
+func ts() int64 {
+	return time.Now().UnixNano()
+}
+
+// export converts a title to its public representation.
+func (t *title) export() *Title {
+	return &Title{
+		ID:               t.id,
+		SHA256:           t.sha256,
+		Path:             t.path,
+		PagesNumber:      len(t.pages),
+		BadPagesNumber:   t.pagesNumBadNames,
+		NamesOccurrences: t.namesNum,
+		Language:         t.lang,
+		Unchanged:        t.unchanged,
+	}
+}
+
+// occurrences collects names found in all pages of a title.
+func (t *title) occurrences() []Occurrence {
+	res := make([]Occurrence, 0, t.namesNum)
+	for _, p := range t.pages {
+		for i := range p.res.Names {
+			res = append(res, newOccurrence(p, i))
 		}
 	}
+	return res
+}
+
+// newOccurrence processes output from name-finding and parsing of the i-th
+// name of a page to prepare it for htindex output.
+func newOccurrence(p page, i int) Occurrence {
+	n := p.res.Names[i]
+	parsed := p.parsed[i]
+	occ := Occurrence{
+		PageID:          p.id,
+		Verbatim:        n.Verbatim,
+		NameString:      n.Name,
+		OffsetStart:     n.OffsetStart,
+		OffsetEnd:       n.OffsetEnd,
+		WordsBefore:     n.WordsBefore,
+		WordsAfter:      n.WordsAfter,
+		AnnotNomen:      n.AnnotNomen,
+		Odds:            n.Odds,
+		Kind:            n.Type,
+		ConfidenceClass: confidenceClass(n.Odds),
+		Canonical:       parsed.Canonical,
+		CanonicalFull:   parsed.CanonicalFull,
+		Cardinality:     parsed.Cardinality,
+		Quality:         parsed.Quality,
+		NameID:          parsed.ID,
+		Language:        p.lang,
+		InHeader:        inLines(p.headers, n.OffsetStart),
+		PageLabel:       p.meta.Label,
+		TimeStamp:       ts(),
+	}
+	if p.matches != nil {
+		m := p.matches[i]
+		occ.MatchID = m.ID
+		occ.AcceptedName = m.AcceptedName
+		occ.MatchType = m.Type
+		occ.DataSource = m.DataSource
+	}
+	if p.endPages != nil {
+		occ.EndPageID = p.endPages[i]
+	}
+	if p.expansions != nil {
+		occ.ExpandedName = p.expansions[i].name
+		occ.ExpansionConfidence = p.expansions[i].confidence
+	}
+	return occ
+}
+
+// Odds that separate confidence classes of names. Names with odds
+// below confidenceMedium are in the low class.
+const (
+	confidenceHigh   = 10000
+	confidenceMedium = 100
+)
+
+// confidenceClass returns the confidence class of a name with given
+// odds. Names found without Bayes algorithms have zero odds, their class
+// is unknown.
+func confidenceClass(odds float64) string {
+	switch {
+	case odds == 0:
+		return "unknown"
+	case odds >= confidenceHigh:
+		return "high"
+	case odds >= confidenceMedium:
+		return "medium"
+	default:
+		return "low"
+	}
 }
 
-// ts generates a converted to a string timestamp in nanoseconds from epoch.
-func ts() string {
-	t := time.Now()
-	return strconv.FormatInt(t.UnixNano(), 10)
-}
-
-// newDetectedName processes output from name-finding to prepare it for
-// htindex output.
-func newDetectedName(p page, n output.Name) detectedName {
-	dn := detectedName{
-		pageID:      p.id,
-		verbatim:    n.Verbatim,
-		nameString:  n.Name,
-		offsetStart: n.OffsetStart,
-		offsetEnd:   n.OffsetEnd,
-		wordsBefore: strings.Join(n.WordsBefore, "|"),
-		wordsAfter:  strings.Join(n.WordsAfter, "|"),
-		annotNomen:  n.AnnotNomen,
-		odds:        n.Odds,
-		kind:        n.Type,
-		timestamp:   ts(),
+// summarize aggregates occurrences of a title by name-strings. Names are
+// sorted by the number of occurrences, and then alphabetically. Occurrences
+// have to be ordered by pages.
+func summarize(occs []Occurrence) []NameSummary {
+	idx := make(map[string]int)
+	res := make([]NameSummary, 0)
+	for _, o := range occs {
+		i, ok := idx[o.NameString]
+		if !ok {
+			i = len(res)
+			idx[o.NameString] = i
+			res = append(res, NameSummary{
+				NameString:  o.NameString,
+				FirstPageID: o.PageID,
+				MaxOdds:     o.Odds,
+			})
+		}
+		sum := &res[i]
+		sum.OccurrencesNumber++
+		if sum.PagesNumber == 0 || sum.LastPageID != o.PageID {
+			sum.PagesNumber++
+			sum.LastPageID = o.PageID
+		}
+		if o.Odds > sum.MaxOdds {
+			sum.MaxOdds = o.Odds
+		}
 	}
-	return dn
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].OccurrencesNumber == res[j].OccurrencesNumber {
+			return res[i].NameString < res[j].NameString
+		}
+		return res[i].OccurrencesNumber > res[j].OccurrencesNumber
+	})
+	return res
+}
+
+// formatTS converts a timestamp to a string.
+func formatTS(ts int64) string {
+	return strconv.FormatInt(ts, 10)
 }
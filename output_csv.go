@@ -0,0 +1,238 @@
+package htindex
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// resultsHeader contains fields of results.csv file.
+var resultsHeader = []string{
+	"TimeStamp", "ID", "PageID", "Verbatim", "WordsBefore", "NameString",
+	"WordsAfter", "AnnotNomen", "OffsetStart", "OffsetEnd", "Odds", "Kind",
+	"Canonical", "CanonicalFull", "Cardinality", "Quality", "NameID",
+	"Language", "ConfidenceClass", "MatchID", "AcceptedName", "MatchType",
+	"DataSource", "EndPageID", "InHeader", "ExpandedName",
+	"ExpansionConfidence", "PageLabel",
+}
+
+// titlesHeader contains fields of titles.csv file.
+var titlesHeader = []string{
+	"ID", "SHA256", "Path", "PagesNumber", "BadPagesNumber", "NamesOccurences",
+	"Language",
+}
+
+// summaryHeader contains fields of summary.csv file.
+var summaryHeader = []string{
+	"ID", "NameString", "OccurrencesNumber", "FirstPageID", "LastPageID",
+	"PagesNumber", "MaxOdds",
+}
+
+// pagesHeader contains fields of pages.csv file.
+var pagesHeader = []string{
+	"ID", "PageID", "Sequence", "Label", "NonStandardName", "Language", "ByteSize",
+	"CharsNumber", "WordsNumber", "NamesNumber", "AlphaRatio",
+	"DictWordsRatio", "AvgWordLength", "Skipped",
+}
+
+// errorsHeader contains fields of errors.csv file.
+var errorsHeader = []string{"TimeStamp", "TitleID", "PageID", "Error"}
+
+// csvSink saves output to results.csv, titles.csv, summary.csv, pages.csv
+// and errors.csv files.
+// After all data of a title are written, the title is registered in the
+// checkpoint journal.
+type csvSink struct {
+	cp         *checkpoint
+	resFile    *os.File
+	res        *csv.Writer
+	titlesFile *os.File
+	titles     *csv.Writer
+	sumFile    *os.File
+	sum        *csv.Writer
+	pagesFile  *os.File
+	pages      *csv.Writer
+	errsFile   *os.File
+	errs       *csv.Writer
+}
+
+func (hti *HTindex) newCSVSink() (*csvSink, error) {
+	var err error
+	s := &csvSink{}
+	s.resFile, s.res, err = hti.createOutput("results.csv", resultsHeader)
+	if err != nil {
+		return nil, err
+	}
+	s.titlesFile, s.titles, err = hti.createOutput("titles.csv", titlesHeader)
+	if err != nil {
+		return nil, err
+	}
+	s.sumFile, s.sum, err = hti.createOutput("summary.csv", summaryHeader)
+	if err != nil {
+		return nil, err
+	}
+	s.pagesFile, s.pages, err = hti.createOutput("pages.csv", pagesHeader)
+	if err != nil {
+		return nil, err
+	}
+	s.errsFile, s.errs, err = hti.createOutput("errors.csv", errorsHeader)
+	if err != nil {
+		return nil, err
+	}
+	s.cp, err = hti.newCheckpoint()
+	return s, err
+}
+
+func (s *csvSink) WriteTitle(t *Title) error {
+	return s.titles.Write([]string{
+		t.ID, t.SHA256, t.Path, strconv.Itoa(t.PagesNumber),
+		strconv.Itoa(t.BadPagesNumber), strconv.Itoa(t.NamesOccurrences),
+		t.Language,
+	})
+}
+
+func (s *csvSink) WriteOccurrences(titleID string, occs []Occurrence) error {
+	for _, o := range occs {
+		out := []string{
+			formatTS(o.TimeStamp), titleID, o.PageID, o.Verbatim,
+			strings.Join(o.WordsBefore, "|"), o.NameString,
+			strings.Join(o.WordsAfter, "|"), o.AnnotNomen,
+			strconv.Itoa(o.OffsetStart), strconv.Itoa(o.OffsetEnd),
+			formatOdds(o.Odds), o.Kind, o.Canonical, o.CanonicalFull,
+			strconv.Itoa(o.Cardinality), strconv.Itoa(o.Quality), o.NameID,
+			o.Language, o.ConfidenceClass, o.MatchID, o.AcceptedName,
+			o.MatchType, o.DataSource, o.EndPageID,
+			strconv.FormatBool(o.InHeader), o.ExpandedName,
+			o.ExpansionConfidence, o.PageLabel,
+		}
+		if err := s.res.Write(out); err != nil {
+			return err
+		}
+	}
+	return s.saveCheckpoint(titleID)
+}
+
+func (s *csvSink) WriteSummary(titleID string, sum []NameSummary) error {
+	for _, v := range sum {
+		if err := s.sum.Write(summaryRow(titleID, v)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// summaryRow converts a summary of a name to a row of summary.csv file.
+func summaryRow(titleID string, v NameSummary) []string {
+	return []string{
+		titleID, v.NameString, strconv.Itoa(v.OccurrencesNumber),
+		v.FirstPageID, v.LastPageID, strconv.Itoa(v.PagesNumber),
+		formatOdds(v.MaxOdds),
+	}
+}
+
+func (s *csvSink) WritePages(titleID string, pages []Page) error {
+	for _, p := range pages {
+		if err := s.pages.Write(pageRow(titleID, p)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pageRow converts metadata of a page to a row of pages.csv file.
+func pageRow(titleID string, p Page) []string {
+	return []string{
+		titleID, p.ID, strconv.Itoa(p.Sequence), p.Label,
+		strconv.FormatBool(p.NonStandardName), p.Language,
+		strconv.Itoa(p.ByteSize), strconv.Itoa(p.CharsNumber),
+		strconv.Itoa(p.WordsNumber), strconv.Itoa(p.NamesNumber),
+		formatRatio(p.AlphaRatio),
+		formatRatio(p.DictWordsRatio), formatRatio(p.AvgWordLength),
+		strconv.FormatBool(p.Skipped),
+	}
+}
+
+// formatRatio converts ratios and averages to strings with 4 decimal
+// places.
+func formatRatio(f float64) string {
+	return strconv.FormatFloat(f, 'f', 4, 64)
+}
+
+// formatOdds converts odds to a string without loss of precision.
+func formatOdds(odds float64) string {
+	return strconv.FormatFloat(odds, 'g', -1, 64)
+}
+
+func (s *csvSink) WriteError(e *Error) error {
+	return s.errs.Write([]string{
+		formatTS(e.TimeStamp), e.TitleID, e.PageID, e.Message,
+	})
+}
+
+func (s *csvSink) Close() error {
+	for _, cw := range []*csv.Writer{s.res, s.titles, s.sum, s.pages, s.errs} {
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return err
+		}
+	}
+	files := []*os.File{s.resFile, s.titlesFile, s.sumFile, s.pagesFile,
+		s.errsFile}
+	for _, f := range files {
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	return s.cp.close()
+}
+
+// saveCheckpoint flushes results, titles, summary and pages data to disk and
+// registers the title in the checkpoint journal together with the sizes of
+// the files.
+func (s *csvSink) saveCheckpoint(titleID string) error {
+	// the order has to be the same as in checkpointFiles.
+	writers := []*csv.Writer{s.res, s.titles, s.sum, s.pages}
+	files := []*os.File{s.resFile, s.titlesFile, s.sumFile, s.pagesFile}
+	sizes := make([]int64, len(files))
+	for i, f := range files {
+		writers[i].Flush()
+		if err := writers[i].Error(); err != nil {
+			return err
+		}
+		size, err := f.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		sizes[i] = size
+	}
+	return s.cp.add(titleID, sizes...)
+}
+
+// createOutput creates a CSV file in the output directory and writes its
+// header. During a resumed run the file is opened for appending, and the
+// header is written only if the file is empty.
+func (hti *HTindex) createOutput(name string,
+	header []string) (*os.File, *csv.Writer, error) {
+	path := filepath.Join(hti.OutputPath, name)
+	flag := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if hti.Resume {
+		flag = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	f, err := os.OpenFile(path, flag, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+	w := csv.NewWriter(f)
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	if stat.Size() == 0 {
+		_ = w.Write(header)
+	}
+	return f, w, nil
+}
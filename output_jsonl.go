@@ -0,0 +1,125 @@
+package htindex
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// jsonlTitle is a line of titles.jsonl file. It contains title's metadata,
+// summary of found names and pages with their metrics and found names.
+type jsonlTitle struct {
+	*Title
+	Summary []NameSummary `json:"summary"`
+	Pages   []jsonlPage   `json:"pages"`
+}
+
+// jsonlPage contains metrics of a page and names found on it.
+type jsonlPage struct {
+	Page
+	Names []Occurrence `json:"names"`
+}
+
+// jsonlSink saves output to titles.jsonl and errors.jsonl files. A title,
+// its summary and pages are kept until its occurrences arrive, and then
+// written as one line.
+type jsonlSink struct {
+	title      *Title
+	summary    []NameSummary
+	pages      []Page
+	titlesFile *os.File
+	titlesBuf  *bufio.Writer
+	titles     *json.Encoder
+	errsFile   *os.File
+	errsBuf    *bufio.Writer
+	errs       *json.Encoder
+}
+
+func (hti *HTindex) newJSONLSink() (*jsonlSink, error) {
+	var err error
+	s := &jsonlSink{}
+	s.titlesFile, err = os.Create(filepath.Join(hti.OutputPath, "titles.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	s.errsFile, err = os.Create(filepath.Join(hti.OutputPath, "errors.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	s.titlesBuf = bufio.NewWriter(s.titlesFile)
+	s.titles = json.NewEncoder(s.titlesBuf)
+	s.titles.SetEscapeHTML(false)
+	s.errsBuf = bufio.NewWriter(s.errsFile)
+	s.errs = json.NewEncoder(s.errsBuf)
+	s.errs.SetEscapeHTML(false)
+	return s, nil
+}
+
+func (s *jsonlSink) WriteTitle(t *Title) error {
+	s.title = t
+	return nil
+}
+
+func (s *jsonlSink) WriteOccurrences(titleID string, occs []Occurrence) error {
+	t := s.title
+	if t == nil || t.ID != titleID {
+		t = &Title{ID: titleID}
+	}
+	sum := s.summary
+	if sum == nil {
+		sum = make([]NameSummary, 0)
+	}
+	pages := s.pages
+	s.title, s.summary, s.pages = nil, nil, nil
+	jt := jsonlTitle{Title: t, Summary: sum, Pages: make([]jsonlPage, 0)}
+	idx := make(map[string]int)
+	for _, p := range pages {
+		idx[p.ID] = len(jt.Pages)
+		jt.Pages = append(jt.Pages, jsonlPage{Page: p, Names: []Occurrence{}})
+	}
+	for _, o := range occs {
+		if o.WordsBefore == nil {
+			o.WordsBefore = []string{}
+		}
+		if o.WordsAfter == nil {
+			o.WordsAfter = []string{}
+		}
+		i, ok := idx[o.PageID]
+		if !ok {
+			i = len(jt.Pages)
+			idx[o.PageID] = i
+			jt.Pages = append(jt.Pages, jsonlPage{Page: Page{ID: o.PageID}})
+		}
+		jt.Pages[i].Names = append(jt.Pages[i].Names, o)
+	}
+	return s.titles.Encode(jt)
+}
+
+func (s *jsonlSink) WritePages(titleID string, pages []Page) error {
+	s.pages = pages
+	return nil
+}
+
+func (s *jsonlSink) WriteSummary(titleID string, sum []NameSummary) error {
+	s.summary = sum
+	return nil
+}
+
+func (s *jsonlSink) WriteError(e *Error) error {
+	return s.errs.Encode(e)
+}
+
+func (s *jsonlSink) Close() error {
+	for _, b := range []*bufio.Writer{s.titlesBuf, s.errsBuf} {
+		if err := b.Flush(); err != nil {
+			return err
+		}
+	}
+	for _, f := range []*os.File{s.titlesFile, s.errsFile} {
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
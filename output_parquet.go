@@ -0,0 +1,260 @@
+package htindex
+
+import (
+	"path/filepath"
+
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/source"
+	pqwriter "github.com/xitongsys/parquet-go/writer"
+)
+
+// parquetResult is a row of results.parquet file.
+type parquetResult struct {
+	TimeStamp           int64    `parquet:"name=TimeStamp, type=INT64"`
+	ID                  string   `parquet:"name=ID, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	PageID              string   `parquet:"name=PageID, type=UTF8"`
+	Verbatim            string   `parquet:"name=Verbatim, type=UTF8"`
+	WordsBefore         []string `parquet:"name=WordsBefore, type=LIST, valuetype=UTF8"`
+	NameString          string   `parquet:"name=NameString, type=UTF8"`
+	WordsAfter          []string `parquet:"name=WordsAfter, type=LIST, valuetype=UTF8"`
+	AnnotNomen          string   `parquet:"name=AnnotNomen, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	OffsetStart         int32    `parquet:"name=OffsetStart, type=INT32"`
+	OffsetEnd           int32    `parquet:"name=OffsetEnd, type=INT32"`
+	Odds                float64  `parquet:"name=Odds, type=DOUBLE"`
+	Kind                string   `parquet:"name=Kind, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	Canonical           string   `parquet:"name=Canonical, type=UTF8"`
+	CanonicalFull       string   `parquet:"name=CanonicalFull, type=UTF8"`
+	Cardinality         int32    `parquet:"name=Cardinality, type=INT32"`
+	Quality             int32    `parquet:"name=Quality, type=INT32"`
+	NameID              string   `parquet:"name=NameID, type=UTF8"`
+	Language            string   `parquet:"name=Language, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	ConfidenceClass     string   `parquet:"name=ConfidenceClass, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	MatchID             string   `parquet:"name=MatchID, type=UTF8"`
+	AcceptedName        string   `parquet:"name=AcceptedName, type=UTF8"`
+	MatchType           string   `parquet:"name=MatchType, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	DataSource          string   `parquet:"name=DataSource, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	EndPageID           string   `parquet:"name=EndPageID, type=UTF8"`
+	InHeader            bool     `parquet:"name=InHeader, type=BOOLEAN"`
+	ExpandedName        string   `parquet:"name=ExpandedName, type=UTF8"`
+	ExpansionConfidence string   `parquet:"name=ExpansionConfidence, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	PageLabel           string   `parquet:"name=PageLabel, type=UTF8"`
+}
+
+// parquetTitle is a row of titles.parquet file.
+type parquetTitle struct {
+	ID              string `parquet:"name=ID, type=UTF8"`
+	SHA256          string `parquet:"name=SHA256, type=UTF8"`
+	Path            string `parquet:"name=Path, type=UTF8"`
+	PagesNumber     int32  `parquet:"name=PagesNumber, type=INT32"`
+	BadPagesNumber  int32  `parquet:"name=BadPagesNumber, type=INT32"`
+	NamesOccurences int32  `parquet:"name=NamesOccurences, type=INT32"`
+	Language        string `parquet:"name=Language, type=UTF8"`
+}
+
+// parquetSummary is a row of summary.parquet file.
+type parquetSummary struct {
+	ID                string  `parquet:"name=ID, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	NameString        string  `parquet:"name=NameString, type=UTF8"`
+	OccurrencesNumber int32   `parquet:"name=OccurrencesNumber, type=INT32"`
+	FirstPageID       string  `parquet:"name=FirstPageID, type=UTF8"`
+	LastPageID        string  `parquet:"name=LastPageID, type=UTF8"`
+	PagesNumber       int32   `parquet:"name=PagesNumber, type=INT32"`
+	MaxOdds           float64 `parquet:"name=MaxOdds, type=DOUBLE"`
+}
+
+// parquetPage is a row of pages.parquet file.
+type parquetPage struct {
+	ID              string  `parquet:"name=ID, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	PageID          string  `parquet:"name=PageID, type=UTF8"`
+	Sequence        int32   `parquet:"name=Sequence, type=INT32"`
+	Label           string  `parquet:"name=Label, type=UTF8"`
+	NonStandardName bool    `parquet:"name=NonStandardName, type=BOOLEAN"`
+	Language        string  `parquet:"name=Language, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	ByteSize        int32   `parquet:"name=ByteSize, type=INT32"`
+	CharsNumber     int32   `parquet:"name=CharsNumber, type=INT32"`
+	WordsNumber     int32   `parquet:"name=WordsNumber, type=INT32"`
+	NamesNumber     int32   `parquet:"name=NamesNumber, type=INT32"`
+	AlphaRatio      float64 `parquet:"name=AlphaRatio, type=DOUBLE"`
+	DictWordsRatio  float64 `parquet:"name=DictWordsRatio, type=DOUBLE"`
+	AvgWordLength   float64 `parquet:"name=AvgWordLength, type=DOUBLE"`
+	Skipped         bool    `parquet:"name=Skipped, type=BOOLEAN"`
+}
+
+// parquetError is a row of errors.parquet file.
+type parquetError struct {
+	TimeStamp int64  `parquet:"name=TimeStamp, type=INT64"`
+	TitleID   string `parquet:"name=TitleID, type=UTF8"`
+	PageID    string `parquet:"name=PageID, type=UTF8"`
+	Error     string `parquet:"name=Error, type=UTF8"`
+}
+
+// parquetSink saves output to results.parquet, titles.parquet,
+// summary.parquet, pages.parquet and errors.parquet files.
+type parquetSink struct {
+	files  []source.ParquetFile
+	res    *pqwriter.ParquetWriter
+	titles *pqwriter.ParquetWriter
+	sum    *pqwriter.ParquetWriter
+	pages  *pqwriter.ParquetWriter
+	errs   *pqwriter.ParquetWriter
+}
+
+func (hti *HTindex) newParquetSink() (*parquetSink, error) {
+	var err error
+	s := &parquetSink{}
+	s.res, err = s.create(hti, "results.parquet", new(parquetResult))
+	if err != nil {
+		return nil, err
+	}
+	s.titles, err = s.create(hti, "titles.parquet", new(parquetTitle))
+	if err != nil {
+		return nil, err
+	}
+	s.sum, err = s.create(hti, "summary.parquet", new(parquetSummary))
+	if err != nil {
+		return nil, err
+	}
+	s.pages, err = s.create(hti, "pages.parquet", new(parquetPage))
+	if err != nil {
+		return nil, err
+	}
+	s.errs, err = s.create(hti, "errors.parquet", new(parquetError))
+	return s, err
+}
+
+// create opens a Parquet file in the output directory and prepares its
+// writer using a schema from the given object.
+func (s *parquetSink) create(hti *HTindex, name string,
+	obj interface{}) (*pqwriter.ParquetWriter, error) {
+	f, err := local.NewLocalFileWriter(filepath.Join(hti.OutputPath, name))
+	if err != nil {
+		return nil, err
+	}
+	s.files = append(s.files, f)
+	pw, err := pqwriter.NewParquetWriter(f, obj, 4)
+	if err != nil {
+		return nil, err
+	}
+	pw.RowGroupSize = int64(hti.RowGroupSize) * 1024 * 1024
+	pw.CompressionType = parquet.CompressionCodec_SNAPPY
+	return pw, nil
+}
+
+func (s *parquetSink) WriteTitle(t *Title) error {
+	return s.titles.Write(parquetTitle{
+		ID:              t.ID,
+		SHA256:          t.SHA256,
+		Path:            t.Path,
+		PagesNumber:     int32(t.PagesNumber),
+		BadPagesNumber:  int32(t.BadPagesNumber),
+		NamesOccurences: int32(t.NamesOccurrences),
+		Language:        t.Language,
+	})
+}
+
+func (s *parquetSink) WriteOccurrences(titleID string, occs []Occurrence) error {
+	for _, o := range occs {
+		err := s.res.Write(parquetResult{
+			TimeStamp:           o.TimeStamp,
+			ID:                  titleID,
+			PageID:              o.PageID,
+			Verbatim:            o.Verbatim,
+			WordsBefore:         o.WordsBefore,
+			NameString:          o.NameString,
+			WordsAfter:          o.WordsAfter,
+			AnnotNomen:          o.AnnotNomen,
+			OffsetStart:         int32(o.OffsetStart),
+			OffsetEnd:           int32(o.OffsetEnd),
+			Odds:                o.Odds,
+			Kind:                o.Kind,
+			Canonical:           o.Canonical,
+			CanonicalFull:       o.CanonicalFull,
+			Cardinality:         int32(o.Cardinality),
+			Quality:             int32(o.Quality),
+			NameID:              o.NameID,
+			Language:            o.Language,
+			ConfidenceClass:     o.ConfidenceClass,
+			MatchID:             o.MatchID,
+			AcceptedName:        o.AcceptedName,
+			MatchType:           o.MatchType,
+			DataSource:          o.DataSource,
+			EndPageID:           o.EndPageID,
+			InHeader:            o.InHeader,
+			ExpandedName:        o.ExpandedName,
+			ExpansionConfidence: o.ExpansionConfidence,
+			PageLabel:           o.PageLabel,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *parquetSink) WriteSummary(titleID string, sum []NameSummary) error {
+	for _, v := range sum {
+		err := s.sum.Write(parquetSummary{
+			ID:                titleID,
+			NameString:        v.NameString,
+			OccurrencesNumber: int32(v.OccurrencesNumber),
+			FirstPageID:       v.FirstPageID,
+			LastPageID:        v.LastPageID,
+			PagesNumber:       int32(v.PagesNumber),
+			MaxOdds:           v.MaxOdds,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *parquetSink) WritePages(titleID string, pages []Page) error {
+	for _, p := range pages {
+		err := s.pages.Write(parquetPage{
+			ID:              titleID,
+			PageID:          p.ID,
+			Sequence:        int32(p.Sequence),
+			Label:           p.Label,
+			NonStandardName: p.NonStandardName,
+			Language:        p.Language,
+			ByteSize:        int32(p.ByteSize),
+			CharsNumber:     int32(p.CharsNumber),
+			WordsNumber:     int32(p.WordsNumber),
+			NamesNumber:     int32(p.NamesNumber),
+			AlphaRatio:      p.AlphaRatio,
+			DictWordsRatio:  p.DictWordsRatio,
+			AvgWordLength:   p.AvgWordLength,
+			Skipped:         p.Skipped,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *parquetSink) WriteError(e *Error) error {
+	return s.errs.Write(parquetError{
+		TimeStamp: e.TimeStamp,
+		TitleID:   e.TitleID,
+		PageID:    e.PageID,
+		Error:     e.Message,
+	})
+}
+
+func (s *parquetSink) Close() error {
+	for _, pw := range []*pqwriter.ParquetWriter{s.res, s.titles, s.sum, s.pages,
+		s.errs} {
+		if err := pw.WriteStop(); err != nil {
+			return err
+		}
+	}
+	for _, f := range s.files {
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
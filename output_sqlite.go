@@ -0,0 +1,315 @@
+package htindex
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+
+	// registers "sqlite" driver for database/sql
+	_ "modernc.org/sqlite"
+)
+
+// sqliteFile is the name of the SQLite database in the output directory.
+const sqliteFile = "htindex.sqlite"
+
+// sqliteSchema creates normalized tables of the SQLite output and indexes
+// for queries by name-strings and titles.
+var sqliteSchema = []string{
+	`CREATE TABLE titles (
+		id TEXT PRIMARY KEY,
+		sha256 TEXT,
+		path TEXT,
+		pages_number INTEGER,
+		bad_pages_number INTEGER,
+		names_occurrences INTEGER,
+		language TEXT
+	)`,
+	`CREATE TABLE pages (
+		id INTEGER PRIMARY KEY,
+		title_id TEXT NOT NULL,
+		page_id TEXT NOT NULL,
+		sequence INTEGER,
+		label TEXT,
+		non_standard_name INTEGER,
+		language TEXT,
+		byte_size INTEGER,
+		chars_number INTEGER,
+		words_number INTEGER,
+		names_number INTEGER,
+		alpha_ratio REAL,
+		dict_words_ratio REAL,
+		avg_word_length REAL,
+		skipped INTEGER
+	)`,
+	`CREATE TABLE name_strings (
+		id INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		canonical TEXT,
+		canonical_full TEXT,
+		cardinality INTEGER,
+		quality INTEGER,
+		uuid TEXT,
+		match_id TEXT,
+		accepted_name TEXT,
+		match_type TEXT,
+		data_source TEXT
+	)`,
+	`CREATE TABLE occurrences (
+		id INTEGER PRIMARY KEY,
+		time_stamp INTEGER,
+		page_id INTEGER NOT NULL,
+		name_string_id INTEGER NOT NULL,
+		verbatim TEXT,
+		words_before TEXT,
+		words_after TEXT,
+		annot_nomen TEXT,
+		offset_start INTEGER,
+		offset_end INTEGER,
+		odds REAL,
+		confidence_class TEXT,
+		kind TEXT,
+		end_page_id TEXT,
+		in_header INTEGER,
+		expanded_name TEXT,
+		expansion_confidence TEXT
+	)`,
+	`CREATE TABLE summaries (
+		title_id TEXT NOT NULL,
+		name_string_id INTEGER NOT NULL,
+		occurrences_number INTEGER,
+		first_page_id TEXT,
+		last_page_id TEXT,
+		pages_number INTEGER,
+		max_odds REAL
+	)`,
+	`CREATE TABLE errors (
+		time_stamp INTEGER,
+		title_id TEXT,
+		page_id TEXT,
+		error TEXT
+	)`,
+	`CREATE UNIQUE INDEX idx_pages_title_id ON pages (title_id, page_id)`,
+	`CREATE UNIQUE INDEX idx_name_strings_name ON name_strings (name)`,
+	`CREATE INDEX idx_name_strings_canonical ON name_strings (canonical)`,
+	`CREATE INDEX idx_occurrences_name_string_id ON occurrences (name_string_id)`,
+	`CREATE INDEX idx_occurrences_page_id ON occurrences (page_id)`,
+	`CREATE INDEX idx_summaries_title_id ON summaries (title_id)`,
+	`CREATE INDEX idx_summaries_name_string_id ON summaries (name_string_id)`,
+	`CREATE INDEX idx_errors_title_id ON errors (title_id)`,
+}
+
+// sqliteSink saves output to a SQLite database. Data of every title are
+// saved in one transaction. If the same title appears in the input more
+// than once, only its first copy is saved.
+type sqliteSink struct {
+	db *sql.DB
+	// names keeps IDs of already saved name-strings.
+	names map[string]int64
+	// title keeps metadata of a title until it is saved together with
+	// occurrences.
+	title *Title
+	// summary keeps the summary of a title until its name-strings are
+	// saved together with occurrences.
+	summary []NameSummary
+	// pages keep pages of a title until they are saved together with
+	// occurrences.
+	pages []Page
+}
+
+func (hti *HTindex) newSQLiteSink() (*sqliteSink, error) {
+	path := filepath.Join(hti.OutputPath, sqliteFile)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	for _, q := range sqliteSchema {
+		if _, err = db.Exec(q); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return &sqliteSink{db: db, names: make(map[string]int64)}, nil
+}
+
+func (s *sqliteSink) WriteTitle(t *Title) error {
+	s.title = t
+	return nil
+}
+
+func (s *sqliteSink) WriteSummary(titleID string, sum []NameSummary) error {
+	s.summary = sum
+	return nil
+}
+
+func (s *sqliteSink) WritePages(titleID string, pages []Page) error {
+	s.pages = pages
+	return nil
+}
+
+func (s *sqliteSink) WriteOccurrences(titleID string, occs []Occurrence) error {
+	t, sum, pages := s.title, s.summary, s.pages
+	s.title, s.summary, s.pages = nil, nil, nil
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	isNew, err := insertTitle(tx, t)
+	if err != nil || !isNew {
+		tx.Rollback()
+		return err
+	}
+	newNames := make(map[string]int64)
+	pageIDs, err := s.insertPages(tx, titleID, pages)
+	if err == nil {
+		err = s.insertOccurrences(tx, titleID, occs, pageIDs, newNames)
+	}
+	if err == nil {
+		err = s.insertSummary(tx, titleID, sum, newNames)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	for k, v := range newNames {
+		s.names[k] = v
+	}
+	return nil
+}
+
+// insertTitle saves metadata of a title within a transaction. It returns
+// false if the title is already saved.
+func insertTitle(tx *sql.Tx, t *Title) (bool, error) {
+	res, err := tx.Exec(`INSERT OR IGNORE INTO titles
+		(id, sha256, path, pages_number, bad_pages_number, names_occurrences,
+		language)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		t.ID, t.SHA256, t.Path, t.PagesNumber, t.BadPagesNumber,
+		t.NamesOccurrences, t.Language)
+	if err != nil {
+		return false, err
+	}
+	num, err := res.RowsAffected()
+	return num > 0, err
+}
+
+// insertPages saves pages of a title within a transaction. It returns
+// database IDs of the pages.
+func (s *sqliteSink) insertPages(tx *sql.Tx, titleID string,
+	pages []Page) (map[string]int64, error) {
+	res := make(map[string]int64, len(pages))
+	for _, p := range pages {
+		r, err := tx.Exec(`INSERT INTO pages
+			(title_id, page_id, sequence, label, non_standard_name, language,
+			byte_size, chars_number, words_number, names_number, alpha_ratio,
+			dict_words_ratio, avg_word_length, skipped)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			titleID, p.ID, p.Sequence, p.Label, p.NonStandardName, p.Language,
+			p.ByteSize, p.CharsNumber, p.WordsNumber, p.NamesNumber,
+			p.AlphaRatio, p.DictWordsRatio, p.AvgWordLength, p.Skipped)
+		if err != nil {
+			return nil, err
+		}
+		if res[p.ID], err = r.LastInsertId(); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// insertOccurrences saves name-strings and occurrences of a title within
+// a transaction. Pages that are absent in pageIDs are saved as well. IDs of
+// name-strings created during the transaction are kept in newNames.
+func (s *sqliteSink) insertOccurrences(tx *sql.Tx, titleID string,
+	occs []Occurrence, pageIDs map[string]int64,
+	newNames map[string]int64) error {
+	for _, o := range occs {
+		pageID, ok := pageIDs[o.PageID]
+		if !ok {
+			res, err := tx.Exec(
+				"INSERT INTO pages (title_id, page_id, language) VALUES (?, ?, ?)",
+				titleID, o.PageID, o.Language)
+			if err != nil {
+				return err
+			}
+			if pageID, err = res.LastInsertId(); err != nil {
+				return err
+			}
+			pageIDs[o.PageID] = pageID
+		}
+		nameID, ok := s.names[o.NameString]
+		if !ok {
+			nameID, ok = newNames[o.NameString]
+		}
+		if !ok {
+			res, err := tx.Exec(`INSERT INTO name_strings
+				(name, canonical, canonical_full, cardinality, quality, uuid,
+				match_id, accepted_name, match_type, data_source)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				o.NameString, o.Canonical, o.CanonicalFull, o.Cardinality,
+				o.Quality, o.NameID, o.MatchID, o.AcceptedName, o.MatchType,
+				o.DataSource)
+			if err != nil {
+				return err
+			}
+			if nameID, err = res.LastInsertId(); err != nil {
+				return err
+			}
+			newNames[o.NameString] = nameID
+		}
+		_, err := tx.Exec(`INSERT INTO occurrences
+			(time_stamp, page_id, name_string_id, verbatim, words_before,
+			words_after, annot_nomen, offset_start, offset_end, odds,
+			confidence_class, kind, end_page_id, in_header, expanded_name,
+			expansion_confidence)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			o.TimeStamp, pageID, nameID, o.Verbatim,
+			strings.Join(o.WordsBefore, "|"), strings.Join(o.WordsAfter, "|"),
+			o.AnnotNomen, o.OffsetStart, o.OffsetEnd, o.Odds, o.ConfidenceClass,
+			o.Kind, o.EndPageID, o.InHeader, o.ExpandedName,
+			o.ExpansionConfidence)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertSummary saves the summary of a title within a transaction. All
+// name-strings of the summary have to be saved already.
+func (s *sqliteSink) insertSummary(tx *sql.Tx, titleID string,
+	sum []NameSummary, newNames map[string]int64) error {
+	for _, v := range sum {
+		nameID, ok := s.names[v.NameString]
+		if !ok {
+			nameID = newNames[v.NameString]
+		}
+		_, err := tx.Exec(`INSERT INTO summaries
+			(title_id, name_string_id, occurrences_number, first_page_id,
+			last_page_id, pages_number, max_odds)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			titleID, nameID, v.OccurrencesNumber, v.FirstPageID, v.LastPageID,
+			v.PagesNumber, v.MaxOdds)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sqliteSink) WriteError(e *Error) error {
+	_, err := s.db.Exec(`INSERT INTO errors (time_stamp, title_id, page_id, error)
+		VALUES (?, ?, ?, ?)`, e.TimeStamp, e.TitleID, e.PageID, e.Message)
+	return err
+}
+
+func (s *sqliteSink) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,88 @@
+package htindex
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gnames/gnfinder/dict"
+)
+
+// pageMetrics calculates metadata and OCR quality metrics of a page text.
+// Words are sequences of letters, a word is in the dictionary if it is
+// a common word of European languages or a known specific epithet.
+func pageMetrics(id string, text []byte, d *dict.Dictionary) Page {
+	res := Page{
+		ID:              id,
+		Sequence:        pageSequence(id),
+		NonStandardName: !strings.HasPrefix(id, "00"),
+		ByteSize:        len(text),
+		CharsNumber:     utf8.RuneCount(text),
+	}
+	var letters, visible int
+	for _, r := range string(text) {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		visible++
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	if visible > 0 {
+		res.AlphaRatio = float64(letters) / float64(visible)
+	}
+
+	words := strings.FieldsFunc(string(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	res.WordsNumber = len(words)
+	if len(words) == 0 {
+		return res
+	}
+	var dictWords int
+	for _, w := range words {
+		w = strings.ToLower(w)
+		if _, ok := d.CommonWords[w]; ok {
+			dictWords++
+		} else if _, ok := d.WhiteSpecies[w]; ok {
+			dictWords++
+		}
+	}
+	res.DictWordsRatio = float64(dictWords) / float64(len(words))
+	res.AvgWordLength = float64(letters) / float64(len(words))
+	return res
+}
+
+// pageSequence returns the number of a page from its ID. Only the last six
+// characters of non-standard IDs are guaranteed to be digits. It returns 0
+// if the number cannot be found.
+func pageSequence(id string) int {
+	if i, err := strconv.Atoi(id); err == nil {
+		return i
+	}
+	if len(id) < 6 {
+		return 0
+	}
+	i, _ := strconv.Atoi(id[len(id)-6:])
+	return i
+}
+
+// pageRunes converts texts of pages to runes.
+func pageRunes(pcs []page) [][]rune {
+	res := make([][]rune, len(pcs))
+	for i, p := range pcs {
+		res[i] = []rune(string(p.text))
+	}
+	return res
+}
+
+// pagesMeta returns metadata of all pages of a title.
+func (t *title) pagesMeta() []Page {
+	res := make([]Page, len(t.pages))
+	for i, p := range t.pages {
+		res[i] = p.meta
+	}
+	return res
+}
@@ -0,0 +1,105 @@
+// Package pairtree converts HathiTrust volume IDs to and from paths in
+// a pairtree file hierarchy. It implements character-mapping rules from the
+// Pairtree specification
+// (https://confluence.ucop.edu/display/Curation/PairTree).
+package pairtree
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Root is the name of a directory that contains a pairtree of a namespace.
+const Root = "pairtree_root"
+
+// hexChars are visible ASCII characters that have to be hex-encoded.
+const hexChars = `"*+,<=>?\^|`
+
+// Encode converts an identifier to a form suitable for file names. First,
+// special characters and characters outside of visible ASCII are
+// hex-encoded as '^xx', then '/' becomes '=', ':' becomes '+' and '.'
+// becomes ','.
+func Encode(id string) string {
+	var b strings.Builder
+	for _, c := range []byte(id) {
+		switch {
+		case c < 0x21 || c > 0x7e || strings.IndexByte(hexChars, c) > -1:
+			fmt.Fprintf(&b, "^%02x", c)
+		case c == '/':
+			b.WriteByte('=')
+		case c == ':':
+			b.WriteByte('+')
+		case c == '.':
+			b.WriteByte(',')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// Decode converts an encoded identifier back to its original form.
+func Decode(s string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '=':
+			b.WriteByte('/')
+		case '+':
+			b.WriteByte(':')
+		case ',':
+			b.WriteByte('.')
+		case '^':
+			if i+2 >= len(s) {
+				return "", fmt.Errorf("broken hex encoding in '%s'", s)
+			}
+			h, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+			if err != nil {
+				return "", fmt.Errorf("broken hex encoding in '%s'", s)
+			}
+			b.WriteByte(byte(h))
+			i += 2
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+// ID creates HathiTrust ID out of a path to a volume's zip file. The path
+// is relative to the directory with namespaces, for example
+// 'uc2/pairtree_root/ar/k+/=1/39/60/=t/3b/z6/35/9g/ark+=13960=t3bz6359g/ark+=13960=t3bz6359g.zip'
+// becomes 'uc2.ark:/13960/t3bz6359g'.
+func ID(p string) (string, error) {
+	el := strings.Split(p, "/")
+	if len(el) < 4 || el[1] != Root {
+		return "", fmt.Errorf("'%s' is not a pairtree path", p)
+	}
+	id, err := Decode(el[len(el)-2])
+	if err != nil {
+		return "", err
+	}
+	return el[0] + "." + id, nil
+}
+
+// Path creates a path to a volume's zip file out of its HathiTrust ID. It
+// is the reverse of ID function.
+func Path(htID string) (string, error) {
+	i := strings.Index(htID, ".")
+	if i < 1 || i == len(htID)-1 {
+		return "", fmt.Errorf("'%s' is not a HathiTrust ID", htID)
+	}
+	ns, enc := htID[0:i], Encode(htID[i+1:])
+	el := []string{ns, Root}
+	for j := 0; j < len(enc); j += 2 {
+		end := j + 2
+		if end > len(enc) {
+			end = len(enc)
+		}
+		el = append(el, enc[j:end])
+	}
+	el = append(el, enc, enc+".zip")
+	return path.Join(el...), nil
+}
@@ -0,0 +1,13 @@
+package pairtree_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestPairtree(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Pairtree Suite")
+}
@@ -0,0 +1,76 @@
+package pairtree_test
+
+import (
+	"bufio"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	. "github.com/gnames/htindex/pairtree"
+)
+
+var _ = Describe("Pairtree", func() {
+	DescribeTable("Encode and Decode",
+		func(id string, enc string) {
+			Expect(Encode(id)).To(Equal(enc))
+			res, err := Decode(enc)
+			Expect(err).To(BeNil())
+			Expect(res).To(Equal(id))
+		},
+		Entry("ark", "ark:/13960/t3bz6359g", "ark+=13960=t3bz6359g"),
+		Entry("dots", "acl9167.0001.001", "acl9167,0001,001"),
+		Entry("digits", "39015027528713", "39015027528713"),
+		Entry("special", "a+b=c d", "a^2bb^3dc^20d"),
+		Entry("unicode", "é", "^c3^a9"),
+	)
+
+	It("does not decode broken hex encoding", func() {
+		_, err := Decode("ab^2")
+		Expect(err).ToNot(BeNil())
+		_, err = Decode("ab^zz")
+		Expect(err).ToNot(BeNil())
+	})
+
+	DescribeTable("ID and Path",
+		func(p string, id string) {
+			res, err := ID(p)
+			Expect(err).To(BeNil())
+			Expect(res).To(Equal(id))
+			res, err = Path(id)
+			Expect(err).To(BeNil())
+			Expect(res).To(Equal(p))
+		},
+		Entry("uc2",
+			"uc2/pairtree_root/ar/k+/=1/39/60/=t/3b/z6/35/9g/ark+=13960=t3bz6359g/ark+=13960=t3bz6359g.zip",
+			"uc2.ark:/13960/t3bz6359g"),
+		Entry("miun",
+			"miun/pairtree_root/ac/l9/16/7,/00/01/,0/01/acl9167,0001,001/acl9167,0001,001.zip",
+			"miun.acl9167.0001.001"),
+		Entry("mdp",
+			"mdp/pairtree_root/39/01/50/27/52/87/13/39015027528713/39015027528713.zip",
+			"mdp.39015027528713"),
+	)
+
+	It("finds zip files of all test volumes from their IDs", func() {
+		f, err := os.Open("../testdata/input_paths.txt")
+		Expect(err).To(BeNil())
+		defer f.Close()
+		s := bufio.NewScanner(f)
+		for s.Scan() {
+			id, err := ID(s.Text())
+			Expect(err).To(BeNil())
+			p, err := Path(id)
+			Expect(err).To(BeNil())
+			Expect(p).To(Equal(s.Text()))
+		}
+	})
+
+	It("rejects paths and IDs of a wrong format", func() {
+		_, err := ID("some/path/file.zip")
+		Expect(err).ToNot(BeNil())
+		_, err = Path("noNamespace")
+		Expect(err).ToNot(BeNil())
+	})
+})
@@ -0,0 +1,121 @@
+// Package parser breaks scientific names found in texts into their
+// elements using gnparser's grammar.
+package parser
+
+import (
+	"gitlab.com/gogna/gnparser/grammar"
+	"gitlab.com/gogna/gnparser/output"
+	"gitlab.com/gogna/gnparser/preprocess"
+)
+
+// Parser parses scientific name-strings. It is not safe for concurrent
+// use, every goroutine needs its own Parser.
+type Parser struct {
+	engine *grammar.Engine
+}
+
+// Parsed contains information about a parsed name-string.
+type Parsed struct {
+	// Verbatim is the name-string given to the parser.
+	Verbatim string
+	// Parsed is true if the parsing was successful.
+	Parsed bool
+	// Canonical is a simplified version of a name without authors and
+	// ranks. It is empty if the name was not parsed.
+	Canonical string
+	// CanonicalFull is a canonical form that keeps ranks of infraspecific
+	// epithets and hybrid signs.
+	CanonicalFull string
+	// Cardinality is the number of elements in a name: 1 for uninomials,
+	// 2 for binomials, 3 for trinomials etc. It is 0 for unparsed names,
+	// hybrid formulas, surrogates and names like 'Aus sp.'.
+	Cardinality int
+	// Quality is the quality of parsing: 1 is the best, 4 is the worst,
+	// 0 means the name was not parsed.
+	Quality int
+	// ID is a UUID v5 generated from the name-string. The same name-string
+	// always gets the same ID.
+	ID string
+}
+
+// New creates a new Parser.
+func New() *Parser {
+	e := &grammar.Engine{Buffer: ""}
+	e.Init()
+	return &Parser{engine: e}
+}
+
+// Parse parses a name-string.
+func (p *Parser) Parse(name string) Parsed {
+	o := p.parse(name)
+	res := Parsed{
+		Verbatim: name,
+		Parsed:   o.Parsed,
+		Quality:  o.Quality,
+		ID:       o.NameStringID,
+	}
+	if o.CanonicalName != nil {
+		res.Canonical = o.CanonicalName.Simple
+		res.CanonicalFull = o.CanonicalName.Full
+		res.Cardinality = cardinality(o)
+	}
+	return res
+}
+
+// Canonical returns the canonical form of a name-string, or the
+// name-string itself if it cannot be parsed.
+func (p *Parser) Canonical(name string) string {
+	if c := p.Parse(name).Canonical; c != "" {
+		return c
+	}
+	return name
+}
+
+func (p *Parser) parse(name string) *output.Output {
+	e := p.engine
+	pp := preprocess.Preprocess([]byte(name))
+	if pp.NoParse {
+		e.NewNotParsedScientificNameNode(pp)
+	} else {
+		e.Buffer = string(pp.Body)
+		e.FullReset()
+		if err := e.Parse(); err != nil {
+			e.Error = err
+			e.NewNotParsedScientificNameNode(pp)
+		} else {
+			e.OutputAST()
+			e.NewScientificNameNode()
+		}
+	}
+	e.SN.AddVerbatim(name)
+	return output.NewOutput(e.SN)
+}
+
+// cardinality calculates the number of elements of a name from positions
+// of its words.
+func cardinality(o *output.Output) int {
+	if !o.Parsed || o.Surrogate {
+		return 0
+	}
+	var uninomials, genera, epithets int
+	for _, p := range o.Positions {
+		switch p.Type {
+		case "uninomial":
+			uninomials++
+		case "genus":
+			genera++
+		case "specificEpithet", "infraspecificEpithet":
+			epithets++
+		case "annotationIdentification", "hybridChar":
+			return 0
+		}
+	}
+	switch {
+	case genera == 1:
+		return 1 + epithets
+	case genera == 0 && uninomials > 0:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,59 @@
+package parser_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	. "github.com/gnames/htindex/parser"
+)
+
+var _ = Describe("Parser", func() {
+	p := New()
+
+	DescribeTable("Cardinality",
+		func(name string, card int) {
+			Expect(p.Parse(name).Cardinality).To(Equal(card))
+		},
+		Entry("uninomial", "Bubo", 1),
+		Entry("binomial", "Puma concolor L.", 2),
+		Entry("trinomial", "Quercus alba var. major", 3),
+		Entry("subgenus", "Aus (Bus) cus", 2),
+		Entry("approximation", "Aus sp.", 0),
+		Entry("hybrid formula", "Aus bus × Cus dus", 0),
+		Entry("not a name", "this is not a name", 0),
+	)
+
+	It("creates the same ID for the same name-string", func() {
+		res1 := p.Parse("Puma concolor")
+		res2 := p.Parse("Puma concolor")
+		Expect(res1.ID).To(Equal(res2.ID))
+		Expect(len(res1.ID)).To(Equal(36))
+		Expect(p.Parse("Puma concolor L.").ID).ToNot(Equal(res1.ID))
+	})
+
+	It("creates full canonical form with ranks", func() {
+		res := p.Parse("Quercus alba var. major Smith")
+		Expect(res.Canonical).To(Equal("Quercus alba major"))
+		Expect(res.CanonicalFull).To(Equal("Quercus alba var. major"))
+		Expect(res.Quality).To(Equal(1))
+	})
+
+	DescribeTable("Parse",
+		func(name string, parsed bool, canonical string) {
+			res := p.Parse(name)
+			Expect(res.Verbatim).To(Equal(name))
+			Expect(res.Parsed).To(Equal(parsed))
+			Expect(res.Canonical).To(Equal(canonical))
+		},
+		Entry("uninomial", "Bubo", true, "Bubo"),
+		Entry("binomial", "Puma concolor L.", true, "Puma concolor"),
+		Entry("trinomial", "Quercus alba var. major", true, "Quercus alba major"),
+		Entry("not a name", "this is not a name", false, ""),
+	)
+
+	It("falls back to name-string for canonical form", func() {
+		Expect(p.Canonical("Puma concolor L.")).To(Equal("Puma concolor"))
+		Expect(p.Canonical("this is not a name")).To(Equal("this is not a name"))
+	})
+})
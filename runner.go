@@ -10,32 +10,77 @@ import (
 // Run is the main method for creation of the scientific names index.
 func (hti *HTindex) Run() error {
 	fmt.Printf("Processing with %d 'threads'\n", hti.JobsNum)
+	done, err := hti.restoreCheckpoint()
+	if err != nil {
+		return err
+	}
+	if len(done) > 0 {
+		fmt.Printf("Resuming after %d finished titles\n", len(done))
+	}
+	prev, err := hti.loadPrevious()
+	if err != nil {
+		return err
+	}
+	s, err := hti.newSink()
+	if err != nil {
+		return err
+	}
 	inCh := make(chan string)
-	errCh := make(chan *htiError)
+	errCh := make(chan *Error)
 	outCh := make(chan *title)
 	var wg sync.WaitGroup
 	var wgOut sync.WaitGroup
 	wg.Add(hti.JobsNum)
 	wgOut.Add(2)
-	go hti.outputError(errCh, &wgOut)
-	go hti.outputResult(outCh, &wgOut)
+	go hti.outputError(errCh, s, &wgOut)
+	sc := newStatsCollector()
+	go hti.outputResult(outCh, s, prev, sc, &wgOut)
 	for i := 0; i < hti.JobsNum; i++ {
-		go hti.worker(inCh, outCh, errCh, &wg)
+		go hti.worker(inCh, outCh, errCh, prev, &wg)
+	}
+	if hti.Walk {
+		err = hti.walkRoot(inCh, errCh, done, prev)
+	} else {
+		err = hti.readInput(inCh, errCh, done, prev)
 	}
-	if err := hti.readInput(inCh, errCh); err != nil {
+	if err != nil {
 		return err
 	}
 	wg.Wait()
 	close(outCh)
 	close(errCh)
 	wgOut.Wait()
-	return nil
+	if err = s.Close(); err != nil {
+		return err
+	}
+	if err = hti.mergePrevious(prev, sc); err != nil {
+		return err
+	}
+	return hti.saveStats(sc)
+}
+
+// saveStats creates the statistics report of the run. Titles of a resumed
+// run were processed by different runs, so the report is made from the
+// output files instead.
+func (hti *HTindex) saveStats(sc *statsCollector) error {
+	stats := sc.stats(hti.StatsTop)
+	if hti.Resume {
+		var err error
+		stats, err = NewStatsFromOutput(hti.OutputPath, hti.StatsTop)
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Found %d names in %d titles\n", stats.OccurrencesNumber,
+		stats.TitlesNumber)
+	return stats.Save(hti.OutputPath)
 }
 
 // readInput traverses the input file and sends paths to title's zip files to
-// further processes.
-func (hti *HTindex) readInput(inCh chan<- string,
-	errCh chan<- *htiError) error {
+// further processes. Titles from the done set are skipped. In incremental
+// mode it also registers all titles that are present in the input.
+func (hti *HTindex) readInput(inCh chan<- string, errCh chan<- *Error,
+	done map[string]struct{}, prev *previous) error {
 	file, err := os.Open(hti.InputPath)
 	if err != nil {
 		return err
@@ -44,10 +89,25 @@ func (hti *HTindex) readInput(inCh chan<- string,
 	defer close(inCh)
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
-		inCh <- scanner.Text()
+		sendInput(scanner.Text(), inCh, done, prev)
 	}
 	if err := scanner.Err(); err != nil {
-		errCh <- &htiError{msg: err.Error()}
+		errCh <- &Error{TimeStamp: ts(), Message: err.Error()}
 	}
 	return nil
 }
+
+// sendInput sends a path to a title's zip file to workers, unless the title
+// is in the done set. In incremental mode it also registers the title as
+// present in the input.
+func sendInput(path string, inCh chan<- string, done map[string]struct{},
+	prev *previous) {
+	id := getID(path)
+	if prev != nil {
+		prev.seen[id] = struct{}{}
+	}
+	if _, ok := done[id]; ok {
+		return
+	}
+	inCh <- path
+}
@@ -0,0 +1,246 @@
+// Package server provides a REST API to query results of htindex. It
+// works with an output directory that contains titles.csv, and names.idx
+// and titles.idx created by the index command.
+package server
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gnames/htindex"
+	"github.com/gnames/htindex/nameindex"
+)
+
+// Server answers queries about names and titles of an output directory.
+type Server struct {
+	dir string
+	mux *http.ServeMux
+	// titles contains metadata of titles, where keys are titles' IDs.
+	titles map[string]*htindex.Title
+}
+
+// TitleSummary describes occurrences of a name in a title without
+// details about pages.
+type TitleSummary struct {
+	// ID is HathiTrust ID of the title.
+	ID string `json:"id"`
+	// Count is the number of the name occurrences in the title.
+	Count int `json:"count"`
+}
+
+// SearchResult is the answer to a search of a name.
+type SearchResult struct {
+	// Name is the canonical form of the name.
+	Name string `json:"name"`
+	// Count is the number of the name occurrences in the corpus.
+	Count int `json:"count"`
+	// Titles contain titles where the name was found.
+	Titles []TitleSummary `json:"titles"`
+}
+
+// TitleName describes occurrences of a name in a title.
+type TitleName = nameindex.TitleName
+
+// New creates a Server for an output directory. It loads titles into
+// memory, names are searched in index files on disk.
+func New(dir string) (*Server, error) {
+	s := &Server{
+		dir: dir,
+		mux: http.NewServeMux(),
+	}
+	var err error
+	if s.titles, err = loadTitles(filepath.Join(dir, "titles.csv")); err != nil {
+		return nil, err
+	}
+	for _, f := range []string{nameindex.File, nameindex.TitlesFile} {
+		if _, err = os.Stat(filepath.Join(dir, f)); err != nil {
+			return nil, fmt.Errorf("cannot find %s, run index command first: %s",
+				f, err)
+		}
+	}
+
+	s.mux.HandleFunc("/api/v1/search", s.search)
+	s.mux.HandleFunc("/api/v1/pages", s.pages)
+	s.mux.HandleFunc("/api/v1/title", s.title)
+	s.mux.HandleFunc("/api/v1/title/names", s.names)
+	return s, nil
+}
+
+// ServeHTTP makes Server an http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// search finds titles that mention a name: /api/v1/search?name=Bubo+bubo
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	e, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	res := SearchResult{
+		Name:   e.Name,
+		Count:  e.Count,
+		Titles: make([]TitleSummary, len(e.Titles)),
+	}
+	for i, t := range e.Titles {
+		res.Titles[i] = TitleSummary{ID: t.ID, Count: t.Count}
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// pages lists pages of a title that mention a name:
+// /api/v1/pages?name=Bubo+bubo&title=mdp.39015027528705
+func (s *Server) pages(w http.ResponseWriter, r *http.Request) {
+	id, ok := param(w, r, "title")
+	if !ok {
+		return
+	}
+	e, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	for _, t := range e.Titles {
+		if t.ID == id {
+			writeJSON(w, http.StatusOK, t.Pages)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound,
+		fmt.Sprintf("name '%s' is not found in title '%s'", e.Name, id))
+}
+
+// title returns metadata of a title: /api/v1/title?id=mdp.39015027528705
+func (s *Server) title(w http.ResponseWriter, r *http.Request) {
+	id, ok := param(w, r, "id")
+	if !ok {
+		return
+	}
+	t, ok := s.titles[id]
+	if !ok {
+		writeError(w, http.StatusNotFound,
+			fmt.Sprintf("title '%s' is not found", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+// names lists names found in a title:
+// /api/v1/title/names?id=mdp.39015027528705
+func (s *Server) names(w http.ResponseWriter, r *http.Request) {
+	id, ok := param(w, r, "id")
+	if !ok {
+		return
+	}
+	if _, ok = s.titles[id]; !ok {
+		writeError(w, http.StatusNotFound,
+			fmt.Sprintf("title '%s' is not found", id))
+		return
+	}
+	t, err := nameindex.LookupTitle(s.dir, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	names := []TitleName{}
+	if t != nil {
+		names = t.Names
+	}
+	writeJSON(w, http.StatusOK, names)
+}
+
+// lookup finds a name given in the 'name' parameter of a request. If
+// the name is not found, it writes an error response.
+func (s *Server) lookup(w http.ResponseWriter,
+	r *http.Request) (*nameindex.Entry, bool) {
+	name, ok := param(w, r, "name")
+	if !ok {
+		return nil, false
+	}
+	e, err := nameindex.Lookup(s.dir, name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	if e == nil {
+		writeError(w, http.StatusNotFound,
+			fmt.Sprintf("name '%s' is not found", name))
+		return nil, false
+	}
+	return e, true
+}
+
+// param returns a value of a required parameter of a GET request.
+func param(w http.ResponseWriter, r *http.Request, key string) (string, bool) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed,
+			fmt.Sprintf("method %s is not allowed", r.Method))
+		return "", false
+	}
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		writeError(w, http.StatusBadRequest,
+			fmt.Sprintf("parameter '%s' is required", key))
+		return "", false
+	}
+	return v, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// loadTitles reads metadata of titles from titles.csv.
+func loadTitles(path string) (map[string]*htindex.Title, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read header of %s: %s", path, err)
+	}
+	idx := make(map[string]int)
+	for i, v := range header {
+		idx[v] = i
+	}
+	res := make(map[string]*htindex.Title)
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			return res, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := func(k string) string {
+			if i, ok := idx[k]; ok && i < len(row) {
+				return row[i]
+			}
+			return ""
+		}
+		t := &htindex.Title{
+			ID:       field("ID"),
+			SHA256:   field("SHA256"),
+			Path:     field("Path"),
+			Language: field("Language"),
+		}
+		t.PagesNumber, _ = strconv.Atoi(field("PagesNumber"))
+		t.BadPagesNumber, _ = strconv.Atoi(field("BadPagesNumber"))
+		t.NamesOccurrences, _ = strconv.Atoi(field("NamesOccurences"))
+		res[t.ID] = t
+	}
+}
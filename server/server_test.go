@@ -0,0 +1,139 @@
+package server_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/gnames/htindex"
+	"github.com/gnames/htindex/nameindex"
+	. "github.com/gnames/htindex/server"
+)
+
+var _ = Describe("Server", func() {
+	var dir string
+	var ts *httptest.Server
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "htindex-server")
+		Expect(err).To(BeNil())
+		for _, f := range []string{"results.csv", "titles.csv"} {
+			data, err := ioutil.ReadFile(filepath.Join("testdata", f))
+			Expect(err).To(BeNil())
+			Expect(ioutil.WriteFile(filepath.Join(dir, f), data, 0644)).To(Succeed())
+		}
+		_, err = nameindex.Build(dir)
+		Expect(err).To(BeNil())
+		s, err := New(dir)
+		Expect(err).To(BeNil())
+		ts = httptest.NewServer(s)
+	})
+
+	AfterEach(func() {
+		ts.Close()
+		os.RemoveAll(dir)
+	})
+
+	It("needs the names index", func() {
+		Expect(os.Remove(filepath.Join(dir, nameindex.TitlesFile))).To(Succeed())
+		_, err := New(dir)
+		Expect(err).ToNot(BeNil())
+		Expect(os.Remove(filepath.Join(dir, nameindex.File))).To(Succeed())
+		_, err = New(dir)
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("searches titles by name", func() {
+		var res SearchResult
+		status := get(ts, "/api/v1/search", url.Values{
+			"name": {"Pomatomus saltatrix (Linnaeus, 1766)"},
+		}, &res)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(res.Name).To(Equal("Pomatomus saltatrix"))
+		Expect(res.Count).To(Equal(4))
+		Expect(res.Titles).To(Equal([]TitleSummary{
+			{ID: "mdp.39015027528705", Count: 3},
+			{ID: "uc2.ark:/13960/t6154rj46", Count: 1},
+		}))
+	})
+
+	It("lists pages of a title with a name", func() {
+		var res []nameindex.Page
+		status := get(ts, "/api/v1/pages", url.Values{
+			"name":  {"Pomatomus saltatrix"},
+			"title": {"mdp.39015027528705"},
+		}, &res)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(res).To(Equal([]nameindex.Page{
+			{ID: "00000014", Count: 2}, {ID: "00000020", Count: 1},
+		}))
+	})
+
+	It("returns metadata of a title", func() {
+		var res htindex.Title
+		status := get(ts, "/api/v1/title", url.Values{
+			"id": {"uc2.ark:/13960/t6154rj46"},
+		}, &res)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(res.PagesNumber).To(Equal(120))
+		Expect(res.NamesOccurrences).To(Equal(3))
+	})
+
+	It("lists names of a title", func() {
+		var res []TitleName
+		status := get(ts, "/api/v1/title/names", url.Values{
+			"id": {"uc2.ark:/13960/t6154rj46"},
+		}, &res)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(len(res)).To(Equal(3))
+		names := make(map[string]int)
+		for _, v := range res {
+			names[v.Name] = v.Count
+		}
+		Expect(names).To(HaveKeyWithValue("Bubo bubo", 1))
+		Expect(names).To(HaveKeyWithValue("Quercus alba major", 1))
+
+		status = get(ts, "/api/v1/title/names", url.Values{
+			"id": {"yale.39002007302079"},
+		}, &res)
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(res).To(BeEmpty())
+	})
+
+	It("reports errors", func() {
+		var res map[string]string
+		status := get(ts, "/api/v1/search", url.Values{"name": {"Aaa bbb"}}, &res)
+		Expect(status).To(Equal(http.StatusNotFound))
+		Expect(res["error"]).ToNot(BeEmpty())
+		status = get(ts, "/api/v1/search", nil, &res)
+		Expect(status).To(Equal(http.StatusBadRequest))
+		status = get(ts, "/api/v1/title", url.Values{"id": {"nope"}}, &res)
+		Expect(status).To(Equal(http.StatusNotFound))
+		status = get(ts, "/api/v1/pages", url.Values{
+			"name":  {"Bubo bubo"},
+			"title": {"mdp.39015027528705"},
+		}, &res)
+		Expect(status).To(Equal(http.StatusNotFound))
+		resp, err := http.Post(ts.URL+"/api/v1/title?id=nope", "", nil)
+		Expect(err).To(BeNil())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
+
+func get(ts *httptest.Server, path string, q url.Values, v interface{}) int {
+	resp, err := http.Get(ts.URL + path + "?" + q.Encode())
+	Expect(err).To(BeNil())
+	defer resp.Body.Close()
+	Expect(resp.Header.Get("Content-Type")).To(Equal("application/json"))
+	Expect(json.NewDecoder(resp.Body).Decode(v)).To(Succeed())
+	return resp.StatusCode
+}
@@ -0,0 +1,270 @@
+package htindex
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Stats is a report about names found in the whole corpus.
+type Stats struct {
+	// TitlesNumber is the number of processed titles.
+	TitlesNumber int `json:"titlesNumber"`
+	// OccurrencesNumber is the number of found names.
+	OccurrencesNumber int `json:"occurrencesNumber"`
+	// NameStringsNumber is the number of distinct name-strings.
+	NameStringsNumber int `json:"nameStringsNumber"`
+	// CanonicalsNumber is the number of distinct canonical forms.
+	CanonicalsNumber int `json:"canonicalsNumber"`
+	// TopNames are the most frequent name-strings.
+	TopNames []NameCount `json:"topNames"`
+	// Kinds is the number of occurrences for every kind of name-finding
+	// decision.
+	Kinds map[string]int `json:"kinds"`
+	// Namespaces contains statistics for every HathiTrust namespace.
+	Namespaces map[string]*NamespaceStats `json:"namespaces"`
+	// TitlesWithoutNames contains IDs of titles where no names were found.
+	TitlesWithoutNames []string `json:"titlesWithoutNames"`
+}
+
+// NameCount is the number of occurrences of a name-string.
+type NameCount struct {
+	// NameString is a normalized version of the name.
+	NameString string `json:"nameString"`
+	// Count is the number of the name occurrences.
+	Count int `json:"count"`
+}
+
+// NamespaceStats describes names found in titles of a namespace.
+type NamespaceStats struct {
+	// TitlesNumber is the number of titles in the namespace.
+	TitlesNumber int `json:"titlesNumber"`
+	// OccurrencesNumber is the number of found names.
+	OccurrencesNumber int `json:"occurrencesNumber"`
+	// NameStringsNumber is the number of distinct name-strings.
+	NameStringsNumber int `json:"nameStringsNumber"`
+}
+
+// statsCollector accumulates data for Stats from titles and occurrences.
+type statsCollector struct {
+	titles     int
+	occs       int
+	names      map[string]*nameStats
+	canonicals map[string]struct{}
+	kinds      map[string]int
+	nss        map[string]*NamespaceStats
+	empty      []string
+}
+
+// nameStats keeps the number of occurrences of a name-string and
+// namespaces where the name-string was found, so name-strings are not
+// stored again for every namespace.
+type nameStats struct {
+	count int
+	nss   []*NamespaceStats
+}
+
+func newStatsCollector() *statsCollector {
+	return &statsCollector{
+		names:      make(map[string]*nameStats),
+		canonicals: make(map[string]struct{}),
+		kinds:      make(map[string]int),
+		nss:        make(map[string]*NamespaceStats),
+	}
+}
+
+// addTitle registers a title.
+func (sc *statsCollector) addTitle(t *Title) {
+	sc.titles++
+	sc.namespace(t.ID).TitlesNumber++
+	if t.NamesOccurrences == 0 {
+		sc.empty = append(sc.empty, t.ID)
+	}
+}
+
+// addOccurrences registers names found in a title.
+func (sc *statsCollector) addOccurrences(titleID string, occs []Occurrence) {
+	ns := sc.namespace(titleID)
+	for _, o := range occs {
+		sc.occs++
+		sc.addName(o.NameString, ns)
+		if o.Canonical != "" {
+			sc.canonicals[o.Canonical] = struct{}{}
+		}
+		sc.kinds[o.Kind]++
+		ns.OccurrencesNumber++
+	}
+}
+
+// addName counts an occurrence of a name-string. The name-string is added
+// to the number of name-strings of a namespace the first time it is found
+// there.
+func (sc *statsCollector) addName(name string, ns *NamespaceStats) {
+	n, ok := sc.names[name]
+	if !ok {
+		n = &nameStats{}
+		sc.names[name] = n
+	}
+	n.count++
+	for _, v := range n.nss {
+		if v == ns {
+			return
+		}
+	}
+	n.nss = append(n.nss, ns)
+	ns.NameStringsNumber++
+}
+
+func (sc *statsCollector) namespace(titleID string) *NamespaceStats {
+	name := namespace(titleID)
+	ns, ok := sc.nss[name]
+	if !ok {
+		ns = &NamespaceStats{}
+		sc.nss[name] = ns
+	}
+	return ns
+}
+
+// stats creates a report with a given number of the most frequent names.
+func (sc *statsCollector) stats(top int) *Stats {
+	s := &Stats{
+		TitlesNumber:       sc.titles,
+		OccurrencesNumber:  sc.occs,
+		NameStringsNumber:  len(sc.names),
+		CanonicalsNumber:   len(sc.canonicals),
+		TopNames:           make([]NameCount, 0, len(sc.names)),
+		Kinds:              sc.kinds,
+		Namespaces:         sc.nss,
+		TitlesWithoutNames: append([]string{}, sc.empty...),
+	}
+	for k, v := range sc.names {
+		s.TopNames = append(s.TopNames, NameCount{NameString: k, Count: v.count})
+	}
+	sort.Slice(s.TopNames, func(i, j int) bool {
+		if s.TopNames[i].Count == s.TopNames[j].Count {
+			return s.TopNames[i].NameString < s.TopNames[j].NameString
+		}
+		return s.TopNames[i].Count > s.TopNames[j].Count
+	})
+	if len(s.TopNames) > top {
+		s.TopNames = s.TopNames[:top]
+	}
+	sort.Strings(s.TitlesWithoutNames)
+	return s
+}
+
+// namespace returns HathiTrust namespace of a title ID, for example 'mdp'
+// for 'mdp.39015027528705'.
+func namespace(titleID string) string {
+	if i := strings.Index(titleID, "."); i > 0 {
+		return titleID[:i]
+	}
+	return titleID
+}
+
+// NewStatsFromOutput creates Stats from titles.csv and results.csv files of
+// an existing output directory. The top argument sets the number of the
+// most frequent names in the report.
+func NewStatsFromOutput(dir string, top int) (*Stats, error) {
+	sc := newStatsCollector()
+	err := readCSV(filepath.Join(dir, "titles.csv"),
+		func(row map[string]string) error {
+			num, _ := strconv.Atoi(row["NamesOccurences"])
+			sc.addTitle(&Title{ID: row["ID"], NamesOccurrences: num})
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	occs := make([]Occurrence, 1)
+	err = readCSV(filepath.Join(dir, "results.csv"),
+		func(row map[string]string) error {
+			occs[0] = Occurrence{
+				NameString: row["NameString"],
+				Canonical:  row["Canonical"],
+				Kind:       row["Kind"],
+			}
+			sc.addOccurrences(row["ID"], occs)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return sc.stats(top), nil
+}
+
+// Save writes the report to stats.json and stats.txt files in a given
+// directory.
+func (s *Stats) Save(dir string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "stats.json"), data, 0644)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(filepath.Join(dir, "stats.txt"))
+	if err != nil {
+		return err
+	}
+	if err = s.WriteText(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// WriteText writes a human-readable version of the report.
+func (s *Stats) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Titles:\t%d\n", s.TitlesNumber)
+	fmt.Fprintf(tw, "Titles without names:\t%d\n", len(s.TitlesWithoutNames))
+	fmt.Fprintf(tw, "Names occurrences:\t%d\n", s.OccurrencesNumber)
+	fmt.Fprintf(tw, "Distinct name-strings:\t%d\n", s.NameStringsNumber)
+	fmt.Fprintf(tw, "Distinct canonical forms:\t%d\n", s.CanonicalsNumber)
+
+	fmt.Fprintf(tw, "\nTop %d names:\n", len(s.TopNames))
+	for _, v := range s.TopNames {
+		fmt.Fprintf(tw, "  %s\t%d\n", v.NameString, v.Count)
+	}
+
+	fmt.Fprintln(tw, "\nKinds:")
+	for _, k := range sortedKeys(s.Kinds) {
+		fmt.Fprintf(tw, "  %s\t%d\n", k, s.Kinds[k])
+	}
+
+	fmt.Fprintln(tw, "\nNamespaces:\n  Namespace\tTitles\tOccurrences\tName-strings")
+	nss := make([]string, 0, len(s.Namespaces))
+	for k := range s.Namespaces {
+		nss = append(nss, k)
+	}
+	sort.Strings(nss)
+	for _, k := range nss {
+		ns := s.Namespaces[k]
+		fmt.Fprintf(tw, "  %s\t%d\t%d\t%d\n", k, ns.TitlesNumber,
+			ns.OccurrencesNumber, ns.NameStringsNumber)
+	}
+
+	fmt.Fprintln(tw, "\nTitles without names:")
+	for _, id := range s.TitlesWithoutNames {
+		fmt.Fprintf(tw, "  %s\n", id)
+	}
+	return tw.Flush()
+}
+
+func sortedKeys(m map[string]int) []string {
+	res := make([]string, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
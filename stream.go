@@ -0,0 +1,70 @@
+package htindex
+
+import (
+	"github.com/gnames/gnfinder"
+	"github.com/gnames/gnfinder/lang"
+	"github.com/gnames/gnfinder/output"
+)
+
+// pageSeparator is placed between texts of pages in a title stream, so the
+// last word of a page and the first word of the next page stay separate.
+const pageSeparator = '\n'
+
+// titleStream joins prepared texts of pages from first to last (exclusive)
+// into one continuous text and removes hyphenation of words at line and
+// page breaks. The offsetMap of the stream points to indices of pages in
+// the slice.
+func titleStream(texts []prepared, first, last int) ([]rune, offsetMap) {
+	var b textBuilder
+	for i := first; i < last; i++ {
+		p := texts[i]
+		b.copyText(p.text, p.m, 0, len(p.text))
+		b.add(pageSeparator, i, p.size)
+	}
+	return dehyphenate(b.text, b.m)
+}
+
+// findInStreams finds names in a title processed as a continuous text, so
+// names split by page breaks are found as well. Skipped pages and changes
+// of languages of pages break the text into separate streams, every stream
+// is searched with the language of its pages. Found names are assigned to
+// pages where they start, with offsets in texts of these pages. If a name
+// continues on the next page, the ID of that page is kept as well.
+func (hti *HTindex) findInStreams(gnf *gnfinder.GNfinder, t *title,
+	texts []prepared, langs []lang.Language) {
+	for first := 0; first < len(texts); first++ {
+		if t.pages[first].meta.Skipped {
+			continue
+		}
+		last := first + 1
+		for last < len(texts) && !t.pages[last].meta.Skipped &&
+			langs[last] == langs[first] {
+			last++
+		}
+		text, m := titleStream(texts, first, last)
+		res := gnf.FindNames([]byte(string(text)),
+			gnfinder.OptLanguage(langs[first]))
+		if hti.NoHeuristic || hti.MinOdds > 0 {
+			hti.filterNames(res)
+		}
+		for _, n := range res.Names {
+			t.addStreamName(n, m)
+		}
+		first = last - 1
+	}
+}
+
+// addStreamName converts offsets of a name found in a stream to offsets in
+// the text of its page and adds the name to the page.
+func (t *title) addStreamName(n output.Name, m offsetMap) {
+	start, startPos := m.locate(n.OffsetStart)
+	end, endPos := m.locateEnd(n.OffsetEnd)
+	n.OffsetStart, n.OffsetEnd = startPos, endPos
+	p := &t.pages[start]
+	p.res.Names = append(p.res.Names, n)
+	var endID string
+	if end != start {
+		endID = t.pages[end].id
+	}
+	p.endPages = append(p.endPages, endID)
+}
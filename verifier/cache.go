@@ -0,0 +1,51 @@
+package verifier
+
+import "container/list"
+
+// cache keeps matches of recently verified names. When it is full, the
+// least recently used name is removed. It is not safe for concurrent use.
+type cache struct {
+	size  int
+	order *list.List
+	items map[string]*list.Element
+}
+
+// cacheItem is an element of the cache order.
+type cacheItem struct {
+	name  string
+	match Match
+}
+
+func newCache(size int) *cache {
+	return &cache{
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// get returns a match of a name and marks the name as recently used.
+func (c *cache) get(name string) (Match, bool) {
+	e, ok := c.items[name]
+	if !ok {
+		return Match{}, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*cacheItem).match, true
+}
+
+// add saves a match of a name, removing the least recently used name if
+// the cache is full.
+func (c *cache) add(name string, m Match) {
+	if e, ok := c.items[name]; ok {
+		e.Value.(*cacheItem).match = m
+		c.order.MoveToFront(e)
+		return
+	}
+	c.items[name] = c.order.PushFront(&cacheItem{name: name, match: m})
+	if c.order.Len() > c.size {
+		e := c.order.Back()
+		c.order.Remove(e)
+		delete(c.items, e.Value.(*cacheItem).name)
+	}
+}
@@ -0,0 +1,277 @@
+package verifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HTTP verifies names with a remote name-resolution service that follows
+// GNverifier API, for example
+// https://verifier.globalnames.org/api/v1/verifications. Names are sent in
+// batches, failed requests are retried, and results of recently verified
+// names are cached, so repeated name-strings are not sent to the service
+// again. HTTP is safe for
+// concurrent use, the number of simultaneous requests is limited for all
+// goroutines together.
+type HTTP struct {
+	// URL is the address of the verification endpoint.
+	URL string
+	// DataSources are IDs of data sources used for verification. If it is
+	// empty, the service uses its default data sources.
+	DataSources []int
+	// BatchSize is the maximal number of names in one request.
+	BatchSize int
+	// Retries is the number of repeated attempts for a failed request.
+	Retries int
+	// RetryDelay is the pause before the first repeated attempt. Every next
+	// pause is twice as long.
+	RetryDelay time.Duration
+	// Concurrency is the maximal number of simultaneous requests.
+	Concurrency int
+	// CacheSize is the maximal number of name-strings in the cache. When
+	// the cache is full, the least recently used name-strings are removed.
+	CacheSize int
+
+	client *http.Client
+	sem    chan struct{}
+	mu     sync.Mutex
+	cache  *cache
+}
+
+// HTTPOption sets a field of HTTP verifier.
+type HTTPOption func(*HTTP)
+
+// OptDataSources sets IDs of data sources used for verification.
+func OptDataSources(ids []int) HTTPOption {
+	return func(h *HTTP) {
+		h.DataSources = ids
+	}
+}
+
+// OptBatchSize sets the maximal number of names in one request.
+func OptBatchSize(i int) HTTPOption {
+	return func(h *HTTP) {
+		h.BatchSize = i
+	}
+}
+
+// OptRetries sets the number of repeated attempts for a failed request
+// and the pause before the first of them.
+func OptRetries(i int, delay time.Duration) HTTPOption {
+	return func(h *HTTP) {
+		h.Retries = i
+		h.RetryDelay = delay
+	}
+}
+
+// OptConcurrency sets the maximal number of simultaneous requests.
+func OptConcurrency(i int) HTTPOption {
+	return func(h *HTTP) {
+		h.Concurrency = i
+	}
+}
+
+// OptCacheSize sets the maximal number of name-strings with cached
+// results. It limits memory used by the cache during long runs.
+func OptCacheSize(i int) HTTPOption {
+	return func(h *HTTP) {
+		h.CacheSize = i
+	}
+}
+
+// OptTimeout sets the timeout of one request.
+func OptTimeout(d time.Duration) HTTPOption {
+	return func(h *HTTP) {
+		h.client.Timeout = d
+	}
+}
+
+// NewHTTP creates HTTP verifier for the given URL with default settings,
+// which can be changed by options.
+func NewHTTP(url string, opts ...HTTPOption) *HTTP {
+	h := &HTTP{
+		URL:         url,
+		BatchSize:   500,
+		Retries:     3,
+		RetryDelay:  time.Second,
+		Concurrency: 4,
+		CacheSize:   100000,
+		client:      &http.Client{Timeout: time.Minute},
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.BatchSize < 1 {
+		h.BatchSize = 1
+	}
+	if h.Concurrency < 1 {
+		h.Concurrency = 1
+	}
+	if h.CacheSize < 1 {
+		h.CacheSize = 1
+	}
+	h.cache = newCache(h.CacheSize)
+	h.sem = make(chan struct{}, h.Concurrency)
+	return h
+}
+
+// Verify returns matches for given names. Only names that are not in the
+// cache are sent to the service. If some requests failed, it returns the
+// first error, and names of failed requests get NotVerified type.
+func (h *HTTP) Verify(names []Name) ([]Match, error) {
+	var todo []string
+	found := make(map[string]Match)
+	seen := make(map[string]struct{})
+	h.mu.Lock()
+	for _, n := range names {
+		if m, ok := h.cache.get(n.Name); ok {
+			found[n.Name] = m
+			continue
+		}
+		if _, ok := seen[n.Name]; ok {
+			continue
+		}
+		seen[n.Name] = struct{}{}
+		todo = append(todo, n.Name)
+	}
+	h.mu.Unlock()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	errs := make(chan error, len(todo)/h.BatchSize+1)
+	for start := 0; start < len(todo); start += h.BatchSize {
+		end := start + h.BatchSize
+		if end > len(todo) {
+			end = len(todo)
+		}
+		wg.Add(1)
+		go func(batch []string) {
+			defer wg.Done()
+			h.sem <- struct{}{}
+			defer func() { <-h.sem }()
+			ms, err := h.verifyBatch(batch)
+			if err != nil {
+				errs <- err
+				return
+			}
+			mu.Lock()
+			for i, m := range ms {
+				found[batch[i]] = m
+			}
+			mu.Unlock()
+		}(todo[start:end])
+	}
+	wg.Wait()
+	close(errs)
+	err := <-errs
+
+	res := make([]Match, len(names))
+	for i, n := range names {
+		m, ok := found[n.Name]
+		if !ok {
+			m.Type = NotVerified
+		}
+		res[i] = m
+	}
+	return res, err
+}
+
+// request is the body of a request to the service.
+type request struct {
+	NameStrings []string `json:"nameStrings"`
+	DataSources []int    `json:"dataSources,omitempty"`
+}
+
+// response is the part of the service response used for matches.
+type response struct {
+	Names []struct {
+		Name       string `json:"name"`
+		MatchType  string `json:"matchType"`
+		BestResult *struct {
+			DataSourceTitle string `json:"dataSourceTitleShort"`
+			RecordID        string `json:"recordId"`
+			MatchedName     string `json:"matchedName"`
+			CurrentRecordID string `json:"currentRecordId"`
+			CurrentName     string `json:"currentName"`
+		} `json:"bestResult"`
+	} `json:"names"`
+}
+
+// verifyBatch sends names to the service and saves results to the cache.
+// It returns matches in the order of names.
+func (h *HTTP) verifyBatch(names []string) ([]Match, error) {
+	body, err := json.Marshal(request{NameStrings: names, DataSources: h.DataSources})
+	if err != nil {
+		return nil, err
+	}
+	var resp response
+	if err = h.post(body, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Names) != len(names) {
+		return nil, fmt.Errorf("verification service returned %d results for %d names",
+			len(resp.Names), len(names))
+	}
+	res := make([]Match, len(names))
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, v := range resp.Names {
+		m := Match{Type: v.MatchType}
+		if m.Type == "" {
+			m.Type = NoMatch
+		}
+		if br := v.BestResult; br != nil {
+			m.ID = br.RecordID
+			m.MatchedName = br.MatchedName
+			m.AcceptedID = br.CurrentRecordID
+			m.AcceptedName = br.CurrentName
+			m.DataSource = br.DataSourceTitle
+		}
+		h.cache.add(names[i], m)
+		res[i] = m
+	}
+	return res, nil
+}
+
+// post sends a request and decodes its response. Requests that failed
+// because of network problems or errors of the service are repeated.
+func (h *HTTP) post(body []byte, obj interface{}) error {
+	delay := h.RetryDelay
+	var err error
+	for attempt := 0; attempt <= h.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+		var retry bool
+		retry, err = h.postOnce(body, obj)
+		if err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+// postOnce makes one request. It returns true if a failed request can be
+// repeated.
+func (h *HTTP) postOnce(body []byte, obj interface{}) (bool, error) {
+	resp, err := h.client.Post(h.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(ioutil.Discard, resp.Body)
+		err = fmt.Errorf("verification service returned status %d", resp.StatusCode)
+		retry := resp.StatusCode == http.StatusTooManyRequests ||
+			resp.StatusCode >= 500
+		return retry, err
+	}
+	return false, json.NewDecoder(resp.Body).Decode(obj)
+}
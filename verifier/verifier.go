@@ -0,0 +1,54 @@
+// Package verifier defines verification of found names against reference
+// sources of names, and provides a client for a remote name-resolution
+// service. A local alternative is provided by the checklist package.
+package verifier
+
+// Types of matches between a name and a reference source.
+const (
+	// Exact means that the name-string is the same as a name of the source.
+	Exact = "Exact"
+	// Canonical means that canonical forms of the name-string and of a name
+	// of the source are the same.
+	Canonical = "Canonical"
+	// NoMatch means that the name was not found in the source.
+	NoMatch = "NoMatch"
+	// NotVerified means that verification of the name failed, for example
+	// because the source was not available.
+	NotVerified = "NotVerified"
+)
+
+// Name is a name-string that needs verification.
+type Name struct {
+	// Name is the name-string.
+	Name string
+	// Canonical is the canonical form of the name-string, it is empty if
+	// the name could not be parsed.
+	Canonical string
+}
+
+// Match is a result of verification of a name.
+type Match struct {
+	// ID is the ID of the matched record.
+	ID string
+	// MatchedName is the name of the matched record.
+	MatchedName string
+	// AcceptedID is the ID of the accepted name of the matched record.
+	// If the record is accepted, it is the same as ID.
+	AcceptedID string
+	// AcceptedName is the currently accepted name for the matched record.
+	AcceptedName string
+	// Type is the type of the match, for example Exact, Canonical or
+	// NoMatch.
+	Type string
+	// DataSource is the title of the reference source.
+	DataSource string
+}
+
+// Verifier verifies name-strings against a reference source. It has to be
+// safe for concurrent use.
+type Verifier interface {
+	// Verify returns matches for given names in the same order. If
+	// verification of some names failed, it returns an error together with
+	// matches, where such names have the NotVerified type.
+	Verify(names []Name) ([]Match, error)
+}
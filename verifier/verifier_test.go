@@ -0,0 +1,194 @@
+package verifier_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/gnames/htindex/verifier"
+)
+
+var _ = Describe("HTTP", func() {
+	var st *standIn
+	var srv *httptest.Server
+
+	BeforeEach(func() {
+		st = &standIn{}
+		srv = httptest.NewServer(st)
+	})
+
+	AfterEach(func() {
+		srv.Close()
+	})
+
+	It("verifies names", func() {
+		v := NewHTTP(srv.URL)
+		res, err := v.Verify(names("Pomatomus saltatrix", "Leonora"))
+		Expect(err).To(BeNil())
+		Expect(res[0].Type).To(Equal(Exact))
+		Expect(res[0].ID).To(Equal("1"))
+		Expect(res[0].AcceptedName).To(Equal("Pomatomus saltatrix (Linnaeus, 1766)"))
+		Expect(res[0].DataSource).To(Equal("Stand-in"))
+		Expect(res[1].Type).To(Equal(NoMatch))
+		Expect(res[1].ID).To(Equal(""))
+	})
+
+	It("sends names in batches", func() {
+		v := NewHTTP(srv.URL, OptBatchSize(2))
+		res, err := v.Verify(names("Aus", "Bus", "Cus", "Dus", "Eus"))
+		Expect(err).To(BeNil())
+		Expect(len(res)).To(Equal(5))
+		Expect(st.requests).To(Equal(3))
+		Expect(st.maxBatch).To(Equal(2))
+	})
+
+	It("caches results", func() {
+		v := NewHTTP(srv.URL)
+		_, err := v.Verify(names("Pomatomus saltatrix", "Leonora", "Leonora"))
+		Expect(err).To(BeNil())
+		Expect(st.requests).To(Equal(1))
+		Expect(st.maxBatch).To(Equal(2))
+		res, err := v.Verify(names("Leonora", "Pomatomus saltatrix"))
+		Expect(err).To(BeNil())
+		Expect(st.requests).To(Equal(1))
+		Expect(res[1].Type).To(Equal(Exact))
+	})
+
+	It("limits the size of the cache", func() {
+		v := NewHTTP(srv.URL, OptCacheSize(1))
+		_, err := v.Verify(names("Aus", "Bus"))
+		Expect(err).To(BeNil())
+		Expect(st.requests).To(Equal(1))
+		_, err = v.Verify(names("Bus"))
+		Expect(err).To(BeNil())
+		Expect(st.requests).To(Equal(1))
+		_, err = v.Verify(names("Aus"))
+		Expect(err).To(BeNil())
+		Expect(st.requests).To(Equal(2))
+	})
+
+	It("marks names of failed requests", func() {
+		st.failures = 1
+		v := NewHTTP(srv.URL, OptBatchSize(1), OptConcurrency(1),
+			OptRetries(0, time.Millisecond))
+		res, err := v.Verify(names("Pomatomus saltatrix", "Leonora"))
+		Expect(err).ToNot(BeNil())
+		var notVerified int
+		for _, m := range res {
+			if m.Type == NotVerified {
+				notVerified++
+				continue
+			}
+			Expect(m.Type).To(BeElementOf(Exact, NoMatch))
+		}
+		Expect(notVerified).To(Equal(1))
+	})
+
+	It("retries failed requests", func() {
+		st.failures = 2
+		v := NewHTTP(srv.URL, OptRetries(2, time.Millisecond))
+		res, err := v.Verify(names("Pomatomus saltatrix"))
+		Expect(err).To(BeNil())
+		Expect(res[0].Type).To(Equal(Exact))
+		Expect(st.requests).To(Equal(3))
+	})
+
+	It("gives up after retries", func() {
+		st.failures = 5
+		v := NewHTTP(srv.URL, OptRetries(1, time.Millisecond))
+		_, err := v.Verify(names("Pomatomus saltatrix"))
+		Expect(err).ToNot(BeNil())
+		Expect(st.requests).To(Equal(2))
+	})
+
+	It("limits concurrent requests", func() {
+		st.delay = 10 * time.Millisecond
+		v := NewHTTP(srv.URL, OptBatchSize(1), OptConcurrency(2))
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				_, err := v.Verify(names("Aus", "Bus", "Cus", "Dus"))
+				Expect(err).To(BeNil())
+			}()
+		}
+		wg.Wait()
+		Expect(st.maxActive).To(BeNumerically("<=", 2))
+	})
+})
+
+func names(ns ...string) []Name {
+	res := make([]Name, len(ns))
+	for i, n := range ns {
+		res[i] = Name{Name: n}
+	}
+	return res
+}
+
+// standIn imitates a verification service. It knows only one name.
+type standIn struct {
+	mu        sync.Mutex
+	requests  int
+	maxBatch  int
+	active    int
+	maxActive int
+	failures  int
+	delay     time.Duration
+}
+
+func (s *standIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests++
+	s.active++
+	if s.active > s.maxActive {
+		s.maxActive = s.active
+	}
+	fail := s.failures > 0
+	s.failures--
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.active--
+		s.mu.Unlock()
+	}()
+	time.Sleep(s.delay)
+	if fail {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	var req struct {
+		NameStrings []string `json:"nameStrings"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	if len(req.NameStrings) > s.maxBatch {
+		s.maxBatch = len(req.NameStrings)
+	}
+	s.mu.Unlock()
+	res := make([]map[string]interface{}, len(req.NameStrings))
+	for i, n := range req.NameStrings {
+		res[i] = map[string]interface{}{"name": n, "matchType": "NoMatch"}
+		if n == "Pomatomus saltatrix" {
+			res[i]["matchType"] = "Exact"
+			res[i]["bestResult"] = map[string]string{
+				"dataSourceTitleShort": "Stand-in",
+				"recordId":             "1",
+				"matchedName":          "Pomatomus saltatrix (Linnaeus, 1766)",
+				"currentRecordId":      "1",
+				"currentName":          "Pomatomus saltatrix (Linnaeus, 1766)",
+			}
+		}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"names": res})
+}
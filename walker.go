@@ -0,0 +1,84 @@
+package htindex
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gnames/htindex/pairtree"
+)
+
+// walkRoot traverses pairtree directories of namespaces located in the
+// root directory and sends paths of found zip files to further processes.
+// Paths are relative to the root, the same way as in the input file.
+func (hti *HTindex) walkRoot(inCh chan<- string, errCh chan<- *Error,
+	done map[string]struct{}, prev *previous) error {
+	defer close(inCh)
+	nss, err := hti.namespaces()
+	if err != nil {
+		return err
+	}
+	for _, ns := range nss {
+		root := filepath.Join(hti.RootPrefix, ns, pairtree.Root)
+		err = filepath.Walk(root, func(path string, info os.FileInfo,
+			err error) error {
+			if err != nil {
+				errCh <- &Error{TimeStamp: ts(), Message: err.Error()}
+				return nil
+			}
+			if !info.Mode().IsRegular() || !strings.HasSuffix(path, ".zip") {
+				return nil
+			}
+			rel, err := filepath.Rel(hti.RootPrefix, path)
+			if err != nil {
+				errCh <- &Error{TimeStamp: ts(), Message: err.Error()}
+				return nil
+			}
+			sendInput(filepath.ToSlash(rel), inCh, done, prev)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// namespaces returns names of the root's subdirectories that contain
+// a pairtree and satisfy include and exclude namespace filters.
+func (hti *HTindex) namespaces() ([]string, error) {
+	var res []string
+	dirs, err := ioutil.ReadDir(hti.RootPrefix)
+	if err != nil {
+		return res, err
+	}
+	include := stringSet(hti.Namespaces)
+	exclude := stringSet(hti.ExcludeNamespaces)
+	for _, d := range dirs {
+		ns := d.Name()
+		if !d.IsDir() {
+			continue
+		}
+		if _, ok := include[ns]; len(include) > 0 && !ok {
+			continue
+		}
+		if _, ok := exclude[ns]; ok {
+			continue
+		}
+		pt, err := os.Stat(filepath.Join(hti.RootPrefix, ns, pairtree.Root))
+		if err != nil || !pt.IsDir() {
+			continue
+		}
+		res = append(res, ns)
+	}
+	return res, nil
+}
+
+func stringSet(ss []string) map[string]struct{} {
+	res := make(map[string]struct{}, len(ss))
+	for _, s := range ss {
+		res[s] = struct{}{}
+	}
+	return res
+}
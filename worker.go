@@ -16,6 +16,9 @@ import (
 	"github.com/gnames/gnfinder"
 	"github.com/gnames/gnfinder/lang"
 	"github.com/gnames/gnfinder/output"
+	"github.com/gnames/htindex/pairtree"
+	"github.com/gnames/htindex/parser"
+	"github.com/gnames/htindex/verifier"
 )
 
 // isPage determines if a file represents a page with text from the title.
@@ -26,7 +29,23 @@ var isPage = regexp.MustCompile(`\d{6}\.txt`)
 type page struct {
 	id   string
 	text []byte
+	// lang is the ISO 639-3 code of the detected language of the page.
+	lang string
 	res  *output.Output
+	// parsed contains results of parsing for every name of res.
+	parsed []parser.Parsed
+	// matches contain results of verification for every name of res.
+	matches []verifier.Match
+	// meta contains OCR quality metrics of the page.
+	meta Page
+	// endPages contain IDs of pages where names of res end, if names
+	// continue on the next page. It is used only in stream mode.
+	endPages []string
+	// headers contain lines of running headers and footers of the page.
+	headers []lineRange
+	// expansions contain full forms of names of res with abbreviated
+	// genera.
+	expansions []expansion
 }
 
 // title represents data and metadata from a title/book/volume.
@@ -34,16 +53,12 @@ type title struct {
 	id               string
 	sha256           string
 	path             string
+	lang             string
 	pages            []page
 	namesNum         int
 	pagesNumBadNames int
-}
-
-type htiError struct {
-	ts      string
-	titleID string
-	pageID  string
-	msg     string
+	// unchanged is true if the title did not change since the previous run.
+	unchanged bool
 }
 
 // byID allows to sort page slice using its `id` field.
@@ -58,46 +73,170 @@ func (b byID) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
 // the output. In case if some errors happened during processing, they will be
 // prepared for logging.
 func (hti *HTindex) worker(inCh <-chan string, outCh chan<- *title,
-	errCh chan<- *htiError, wg *sync.WaitGroup) {
+	errCh chan<- *Error, prev *previous, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	opts := []gnfinder.Option{
 		gnfinder.OptDict(hti.Dict),
-		gnfinder.OptBayes(true),
+		gnfinder.OptBayes(hti.Bayes),
+		gnfinder.OptBayesThreshold(hti.BayesOddsThreshold),
 		gnfinder.OptTokensAround(hti.WordsAround),
 		gnfinder.OptLanguage(lang.English),
 	}
 	gnf := gnfinder.NewGNfinder(opts...)
+	gnp := parser.New()
 
 	for zipPath := range inCh {
 		t := title{id: getID(zipPath), path: zipPath}
 		path := filepath.Join(hti.RootPrefix, zipPath)
+		t.sha256 = getSHA256(path)
+		if prev.isUnchanged(&t) {
+			t.unchanged = true
+			outCh <- &t
+			continue
+		}
 		r, err := zip.OpenReader(path)
 		if err != nil {
-			errCh <- &htiError{msg: err.Error(), titleID: t.id, ts: ts()}
+			errCh <- &Error{Message: err.Error(), TitleID: t.id, TimeStamp: ts()}
 		}
 		pcs, pagesNumBadNames := pagesContent(r, errCh)
 		t.pages = make([]page, len(pcs))
 		if pagesNumBadNames > 0 {
 			msg := fmt.Sprintf("non-standard naming for %d pages", pagesNumBadNames)
-			errCh <- &htiError{msg: msg, titleID: t.id, ts: ts()}
+			errCh <- &Error{Message: msg, TitleID: t.id, TimeStamp: ts()}
 		}
 		t.pagesNumBadNames = pagesNumBadNames
 		if len(pcs) == 0 {
-			errCh <- &htiError{ts: ts(), titleID: t.id, msg: "no pages detected"}
+			errCh <- &Error{TimeStamp: ts(), TitleID: t.id, Message: "no pages detected"}
 			continue
 		}
+		var codes []string
+		var langs []lang.Language
+		t.lang, codes, langs = hti.detectLanguages(pcs)
+		var runes [][]rune
+		if hti.Stream || hti.Normalization != 0 || hti.detectHeaders() {
+			runes = pageRunes(pcs)
+		}
+		var headers [][]lineRange
+		if hti.detectHeaders() {
+			headers = runningHeaders(runes)
+		}
+		var texts []prepared
+		if hti.Stream || hti.Normalization != 0 {
+			texts = hti.prepareTexts(runes, headers)
+		}
 		for i, p := range pcs {
 			t.pages[i].id = p.id
-			t.pages[i].res = gnf.FindNames(p.text)
-			t.namesNum += len(t.pages[i].res.Names)
+			if headers != nil {
+				t.pages[i].headers = headers[i]
+			}
+			t.pages[i].lang = codes[i]
+			t.pages[i].meta = pageMetrics(p.id, p.text, hti.Dict)
+			t.pages[i].meta.Language = codes[i]
+			t.pages[i].res = &output.Output{}
+			if t.pages[i].meta.DictWordsRatio < hti.MinPageQuality {
+				t.pages[i].meta.Skipped = true
+				continue
+			}
+			if hti.Stream {
+				continue
+			}
+			if texts != nil {
+				t.pages[i].res = texts[i].findNames(gnf, gnfinder.OptLanguage(langs[i]))
+			} else {
+				t.pages[i].res = gnf.FindNames(p.text, gnfinder.OptLanguage(langs[i]))
+			}
+			if hti.NoHeuristic || hti.MinOdds > 0 {
+				hti.filterNames(t.pages[i].res)
+			}
+		}
+		if hti.METS {
+			t.checkMETS(path, pcs, errCh)
 		}
+		if hti.Stream {
+			hti.findInStreams(gnf, &t, texts, langs)
+		}
+		for i := range t.pages {
+			p := &t.pages[i]
+			if hti.DropHeaderNames {
+				p.dropHeaderNames()
+			}
+			p.parsed = parseNames(gnp, p.res.Names)
+			p.meta.NamesNumber = len(p.res.Names)
+			t.namesNum += len(p.res.Names)
+		}
+		t.expandGenera()
 		r.Close()
-		t.sha256 = getSHA256(path)
+		if hti.Verifier != nil {
+			if err = hti.verifyNames(&t); err != nil {
+				errCh <- &Error{TimeStamp: ts(), TitleID: t.id,
+					Message: "verification failed: " + err.Error()}
+			}
+		}
 		outCh <- &t
 	}
 }
 
+// filterNames removes names with odds below MinOdds. If NoHeuristic is set,
+// it also removes names that were found only by heuristic algorithms, as
+// their Bayes odds are below BayesOddsThreshold.
+func (hti *HTindex) filterNames(res *output.Output) {
+	names := res.Names[:0]
+	for _, n := range res.Names {
+		if n.Odds < hti.MinOdds {
+			continue
+		}
+		if hti.NoHeuristic && n.Odds < hti.BayesOddsThreshold {
+			continue
+		}
+		names = append(names, n)
+	}
+	res.Names = names
+}
+
+// parseNames parses name-strings found on a page.
+func parseNames(gnp *parser.Parser, names []output.Name) []parser.Parsed {
+	res := make([]parser.Parsed, len(names))
+	for i, n := range names {
+		res[i] = gnp.Parse(n.Name)
+	}
+	return res
+}
+
+// verifyNames verifies unique names of a title in one batch and saves
+// matches to pages. If verification fails completely, pages stay without
+// matches, if it fails for some names, they get the NotVerified type.
+func (hti *HTindex) verifyNames(t *title) error {
+	var names []verifier.Name
+	idx := make(map[string]int)
+	for _, p := range t.pages {
+		for i, n := range p.res.Names {
+			if _, ok := idx[n.Name]; ok {
+				continue
+			}
+			idx[n.Name] = len(names)
+			names = append(names, verifier.Name{
+				Name: n.Name, Canonical: p.parsed[i].Canonical,
+			})
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	matches, err := hti.Verifier.Verify(names)
+	if len(matches) != len(names) {
+		return err
+	}
+	for i := range t.pages {
+		p := &t.pages[i]
+		p.matches = make([]verifier.Match, len(p.res.Names))
+		for j, n := range p.res.Names {
+			p.matches[j] = matches[idx[n.Name]]
+		}
+	}
+	return err
+}
+
 func getSHA256(path string) string {
 	f, err := os.Open(path)
 	if err != nil {
@@ -113,7 +252,7 @@ func getSHA256(path string) string {
 
 // pagesContent generates a list of all pages with their texts sorted according
 // to their position in the title.
-func pagesContent(r *zip.ReadCloser, errCh chan<- *htiError) ([]page, int) {
+func pagesContent(r *zip.ReadCloser, errCh chan<- *Error) ([]page, int) {
 	badPageName := 0
 	var pages []page
 	for _, f := range r.File {
@@ -124,7 +263,7 @@ func pagesContent(r *zip.ReadCloser, errCh chan<- *htiError) ([]page, int) {
 		}
 		zf, err := f.Open()
 		if err != nil {
-			errCh <- &htiError{msg: err.Error()}
+			errCh <- &Error{TimeStamp: ts(), Message: err.Error()}
 		}
 		id := fn[fnl-12 : fnl-4]
 		if !strings.HasPrefix(id, "00") {
@@ -132,7 +271,7 @@ func pagesContent(r *zip.ReadCloser, errCh chan<- *htiError) ([]page, int) {
 		}
 		text, err := ioutil.ReadAll(zf)
 		if err != nil {
-			errCh <- &htiError{msg: err.Error()}
+			errCh <- &Error{TimeStamp: ts(), Message: err.Error()}
 		}
 		pages = append(pages, page{id: id, text: text})
 		zf.Close()
@@ -141,8 +280,16 @@ func pagesContent(r *zip.ReadCloser, errCh chan<- *htiError) ([]page, int) {
 	return pages, badPageName
 }
 
-// getID generates the id of a title from its filepath.
+// getID generates HathiTrust ID of a title from its filepath. If the path
+// does not follow pairtree conventions, the ID is made of the first and the
+// penultimate elements of the path.
 func getID(p string) string {
+	if id, err := pairtree.ID(p); err == nil {
+		return id
+	}
 	el := strings.Split(p, "/")
+	if len(el) < 2 {
+		return p
+	}
 	return fmt.Sprintf("%s.%s", el[0], el[len(el)-2])
 }